@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"pixel_tetris/engine"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+const CLEAR_LABELS_TIME = 1500 * time.Millisecond
+
+var (
+	clearLabels      []string
+	startClearLabels time.Time
+)
+
+// ShowClearLabels names the last scoring lock on the board for a while.
+func ShowClearLabels(cl engine.Clear) {
+	//--------------------------------------------------
+	clearLabels = clearLabels[:0]
+	if cl.B2B {
+		clearLabels = append(clearLabels, "B2B")
+	}
+	clearLabels = append(clearLabels, cl.Type.String())
+	if cl.Combo > 0 {
+		clearLabels = append(clearLabels, fmt.Sprintf("COMBO %d", cl.Combo))
+	}
+	if cl.Perfect {
+		clearLabels = append(clearLabels, "PERFECT CLEAR")
+	}
+	clearLabels = append(clearLabels, fmt.Sprintf("+%d", cl.Points))
+	startClearLabels = time.Now()
+}
+
+// DelayClearLabels keeps the labels on for d more, the time of a pause.
+func DelayClearLabels(d time.Duration) {
+	startClearLabels = startClearLabels.Add(d)
+}
+
+func DrawClearLabels(win pixel.Target) {
+
+	if len(clearLabels) == 0 {
+		return
+	}
+	if time.Since(startClearLabels) > CLEAR_LABELS_TIME {
+		clearLabels = clearLabels[:0]
+		return
+	}
+
+	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
+	oy := float64(WIN_HEIGHT - TOP - 4*cellSize)
+	for _, label := range clearLabels {
+		txt := text.New(pixel.V(ox, oy), atlas)
+		txt.Color = colornames.Orange
+		rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+		fmt.Fprintf(txt, "%s", label)
+		txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+		oy -= float64(cellSize + 4)
+	}
+
+}
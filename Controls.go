@@ -0,0 +1,523 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"pixel_tetris/engine"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/pixelgl"
+	"github.com/gopxl/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+const CONTROLS_FILE = "Controls.cfg"
+
+// Control is a named action of the player, bound to one or more keys.
+type Control int
+
+const (
+	CTL_MOVE_LEFT Control = iota
+	CTL_MOVE_RIGHT
+	CTL_ROTATE_CCW
+	CTL_ROTATE_CW
+	CTL_ROTATE_180
+	CTL_SOFT_DROP
+	CTL_HARD_DROP
+	CTL_SONIC_DROP
+	CTL_HOLD
+	CTL_PAUSE
+	CTL_GHOST
+	CTL_MUSIC
+	CTL_VOLUME_UP
+	CTL_VOLUME_DOWN
+	CTL_START
+	CTL_CONTINUE
+	CTL_VALIDATE
+	CTL_BACK
+	CTL_DELETE
+	CTL_MENU_LEFT
+	CTL_MENU_RIGHT
+	CTL_MENU_UP
+	CTL_MENU_DOWN
+	CTL_HIGHSCORES
+	CTL_REPLAY
+	CTL_REPLAY_STEP
+	CTL_REPLAY_FAST
+	CTL_CONTROLS
+	CTL_OPTIONS
+	NB_CONTROLS
+)
+
+var controlNames = [NB_CONTROLS]string{
+	"MOVE_LEFT", "MOVE_RIGHT", "ROTATE_CCW", "ROTATE_CW", "ROTATE_180",
+	"SOFT_DROP", "HARD_DROP", "SONIC_DROP", "HOLD", "PAUSE", "GHOST",
+	"MUSIC", "VOLUME_UP", "VOLUME_DOWN", "START", "CONTINUE",
+	"VALIDATE", "BACK", "DELETE",
+	"MENU_LEFT", "MENU_RIGHT", "MENU_UP", "MENU_DOWN", "HIGHSCORES", "REPLAY",
+	"REPLAY_STEP", "REPLAY_FAST", "CONTROLS", "OPTIONS"}
+
+var defaultKeys = [NB_CONTROLS][]pixelgl.Button{
+	CTL_MOVE_LEFT:   {pixelgl.KeyLeft},
+	CTL_MOVE_RIGHT:  {pixelgl.KeyRight},
+	CTL_ROTATE_CCW:  {pixelgl.KeyUp},
+	CTL_ROTATE_CW:   {pixelgl.KeyX},
+	CTL_ROTATE_180:  {pixelgl.KeyA},
+	CTL_SOFT_DROP:   {pixelgl.KeyDown},
+	CTL_HARD_DROP:   {pixelgl.KeySpace},
+	CTL_SONIC_DROP:  {pixelgl.KeyS},
+	CTL_HOLD:        {pixelgl.KeyC},
+	CTL_PAUSE:       {pixelgl.KeyP},
+	CTL_GHOST:       {pixelgl.KeyG},
+	CTL_MUSIC:       {pixelgl.KeyPause},
+	CTL_VOLUME_UP:   {pixelgl.KeyKPAdd},
+	CTL_VOLUME_DOWN: {pixelgl.KeyKPSubtract},
+	CTL_START:       {pixelgl.KeySpace},
+	CTL_CONTINUE:    {pixelgl.KeyC},
+	CTL_VALIDATE:    {pixelgl.KeyEnter, pixelgl.KeyKPEnter},
+	CTL_BACK:        {pixelgl.KeyEscape},
+	CTL_DELETE:      {pixelgl.KeyBackspace},
+	CTL_MENU_LEFT:   {pixelgl.KeyLeft},
+	CTL_MENU_RIGHT:  {pixelgl.KeyRight},
+	CTL_MENU_UP:     {pixelgl.KeyUp},
+	CTL_MENU_DOWN:   {pixelgl.KeyDown},
+	CTL_HIGHSCORES:  {pixelgl.KeyH},
+	CTL_REPLAY:      {pixelgl.KeyR},
+	CTL_REPLAY_STEP: {pixelgl.KeyN},
+	CTL_REPLAY_FAST: {pixelgl.KeyF},
+	CTL_CONTROLS:    {pixelgl.KeyK},
+	CTL_OPTIONS:     {pixelgl.KeyO},
+}
+
+// Gamepad buttons of each control, following the GLFW gamepad layout.
+var defaultPadButtons = [NB_CONTROLS][]pixelgl.GamepadButton{
+	CTL_MOVE_LEFT:   {pixelgl.ButtonDpadLeft},
+	CTL_MOVE_RIGHT:  {pixelgl.ButtonDpadRight},
+	CTL_ROTATE_CCW:  {pixelgl.ButtonB},
+	CTL_ROTATE_CW:   {pixelgl.ButtonA},
+	CTL_ROTATE_180:  {pixelgl.ButtonY},
+	CTL_SOFT_DROP:   {pixelgl.ButtonDpadDown},
+	CTL_HARD_DROP:   {pixelgl.ButtonDpadUp},
+	CTL_HOLD:        {pixelgl.ButtonLeftBumper, pixelgl.ButtonRightBumper},
+	CTL_PAUSE:       {pixelgl.ButtonStart},
+	CTL_START:       {pixelgl.ButtonStart, pixelgl.ButtonA},
+	CTL_CONTINUE:    {pixelgl.ButtonRightBumper},
+	CTL_VALIDATE:    {pixelgl.ButtonStart, pixelgl.ButtonA},
+	CTL_BACK:        {pixelgl.ButtonBack},
+	CTL_DELETE:      {pixelgl.ButtonX},
+	CTL_MENU_LEFT:   {pixelgl.ButtonDpadLeft},
+	CTL_MENU_RIGHT:  {pixelgl.ButtonDpadRight},
+	CTL_MENU_UP:     {pixelgl.ButtonDpadUp},
+	CTL_MENU_DOWN:   {pixelgl.ButtonDpadDown},
+	CTL_HIGHSCORES:  {pixelgl.ButtonY},
+	CTL_REPLAY:      {pixelgl.ButtonX},
+	CTL_REPLAY_STEP: {pixelgl.ButtonRightBumper},
+	CTL_REPLAY_FAST: {pixelgl.ButtonLeftBumper},
+	CTL_OPTIONS:     {pixelgl.ButtonLeftBumper},
+}
+
+// Engine action sent by each control while playing, and whether its
+// release is sent too. PAUSE goes through the pause menu.
+var playActions = []struct {
+	control  Control
+	action   engine.Action
+	fRelease bool
+}{
+	{CTL_MOVE_LEFT, engine.MOVE_LEFT, true},
+	{CTL_MOVE_RIGHT, engine.MOVE_RIGHT, true},
+	{CTL_ROTATE_CCW, engine.ROTATE_LEFT, false},
+	{CTL_ROTATE_CW, engine.ROTATE_RIGHT, false},
+	{CTL_ROTATE_180, engine.ROTATE_180, false},
+	{CTL_SOFT_DROP, engine.FAST_DOWN, true},
+	{CTL_HARD_DROP, engine.HARD_DROP, false},
+	{CTL_SONIC_DROP, engine.SONIC_DROP, false},
+	{CTL_HOLD, engine.HOLD, false},
+}
+
+func (c Control) String() string {
+	return controlNames[c]
+}
+
+// Buttons is the part of pixelgl.Window the controls read: the keyboard
+// and the gamepads.
+type Buttons interface {
+	Pressed(button pixelgl.Button) bool
+	JustPressed(button pixelgl.Button) bool
+	JustReleased(button pixelgl.Button) bool
+	JoystickPresent(js pixelgl.Joystick) bool
+	JoystickPressed(js pixelgl.Joystick, button pixelgl.GamepadButton) bool
+	JoystickJustPressed(js pixelgl.Joystick, button pixelgl.GamepadButton) bool
+	JoystickJustReleased(js pixelgl.Joystick, button pixelgl.GamepadButton) bool
+}
+
+// KeyChar is a key typing a character of a player name.
+type KeyChar struct {
+	keycode pixelgl.Button
+	c       string
+}
+
+type Controls struct {
+	keys       [NB_CONTROLS][]pixelgl.Button
+	padButtons [NB_CONTROLS][]pixelgl.GamepadButton
+	keyChars   []KeyChar
+}
+
+func ControlsNew() *Controls {
+	ct := &Controls{}
+	ct.SetDefaults()
+	for b := pixelgl.KeyA; b <= pixelgl.KeyZ; b++ {
+		ct.keyChars = append(ct.keyChars, KeyChar{keycode: b, c: string(rune('A' + b - pixelgl.KeyA))})
+	}
+	for b := pixelgl.Key0; b <= pixelgl.Key9; b++ {
+		ct.keyChars = append(ct.keyChars, KeyChar{keycode: b, c: string(rune('0' + b - pixelgl.Key0))})
+	}
+	for b := pixelgl.KeyKP0; b <= pixelgl.KeyKP9; b++ {
+		ct.keyChars = append(ct.keyChars, KeyChar{keycode: b, c: string(rune('0' + b - pixelgl.KeyKP0))})
+	}
+	ct.keyChars = append(ct.keyChars, KeyChar{keycode: pixelgl.KeySpace, c: " "})
+	return ct
+}
+
+func (ct *Controls) SetDefaults() {
+	for c := range ct.keys {
+		ct.keys[c] = append([]pixelgl.Button(nil), defaultKeys[c]...)
+		ct.padButtons[c] = append([]pixelgl.GamepadButton(nil), defaultPadButtons[c]...)
+	}
+}
+
+func (ct *Controls) Keys(c Control) []pixelgl.Button {
+	return ct.keys[c]
+}
+
+// Bind makes b the only key of c.
+func (ct *Controls) Bind(c Control, b pixelgl.Button) {
+	ct.keys[c] = []pixelgl.Button{b}
+}
+
+func (ct *Controls) Pressed(bt Buttons, c Control) bool {
+	for _, b := range ct.keys[c] {
+		if bt.Pressed(b) {
+			return true
+		}
+	}
+	return ct.padState(bt, c, bt.JoystickPressed)
+}
+
+func (ct *Controls) JustPressed(bt Buttons, c Control) bool {
+	for _, b := range ct.keys[c] {
+		if bt.JustPressed(b) {
+			return true
+		}
+	}
+	return ct.padState(bt, c, bt.JoystickJustPressed)
+}
+
+func (ct *Controls) JustReleased(bt Buttons, c Control) bool {
+	for _, b := range ct.keys[c] {
+		if bt.JustReleased(b) {
+			return true
+		}
+	}
+	return ct.padState(bt, c, bt.JoystickJustReleased)
+}
+
+// AnyJustPressed tells whether any key, mouse button or gamepad button was
+// pressed since the last frame, bound to a control or not.
+func AnyJustPressed(bt Buttons) bool {
+	//--------------------------------------------------
+	for b := pixelgl.Button(0); b <= pixelgl.KeyLast; b++ {
+		if bt.JustPressed(b) {
+			return true
+		}
+	}
+	for js := pixelgl.Joystick1; js <= pixelgl.JoystickLast; js++ {
+		if !bt.JoystickPresent(js) {
+			continue
+		}
+		for b := pixelgl.GamepadButton(0); b <= pixelgl.ButtonLast; b++ {
+			if bt.JoystickJustPressed(js, b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// padState tells whether state holds for a button of c on any connected
+// gamepad.
+func (ct *Controls) padState(bt Buttons, c Control,
+	state func(js pixelgl.Joystick, button pixelgl.GamepadButton) bool) bool {
+	//--------------------------------------------------
+	if len(ct.padButtons[c]) == 0 {
+		return false
+	}
+	for js := pixelgl.Joystick1; js <= pixelgl.JoystickLast; js++ {
+		if !bt.JoystickPresent(js) {
+			continue
+		}
+		for _, b := range ct.padButtons[c] {
+			if state(js, b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PlayInputs appends to inputs the engine actions of the controls pressed
+// or released since the last frame.
+func (ct *Controls) PlayInputs(bt Buttons, inputs []engine.InputEvent) []engine.InputEvent {
+	//--------------------------------------------------
+	for _, pa := range playActions {
+		if ct.JustPressed(bt, pa.control) {
+			inputs = append(inputs, engine.InputEvent{Action: pa.action, Pressed: true})
+		}
+		if pa.fRelease && ct.JustReleased(bt, pa.control) {
+			inputs = append(inputs, engine.InputEvent{Action: pa.action, Pressed: false})
+		}
+	}
+	return inputs
+}
+
+// Typed returns the characters typed for a player name, leaving out the
+// keys bound to the reserved controls.
+func (ct *Controls) Typed(bt Buttons, reserved ...Control) string {
+	//--------------------------------------------------
+	var typed string
+	for _, k := range ct.keyChars {
+		if !bt.JustPressed(k.keycode) || ct.isReserved(k.keycode, reserved) {
+			continue
+		}
+		typed += k.c
+	}
+	return typed
+}
+
+func (ct *Controls) isReserved(b pixelgl.Button, reserved []Control) bool {
+	for _, c := range reserved {
+		for _, k := range ct.keys[c] {
+			if k == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ButtonOf looks a key up by the name pixelgl gives it.
+func ButtonOf(name string) (pixelgl.Button, bool) {
+	for b := pixelgl.Button(0); b <= pixelgl.KeyLast; b++ {
+		if s := b.String(); s != "Invalid" && strings.EqualFold(s, name) {
+			return b, true
+		}
+	}
+	return 0, false
+}
+
+// Save writes a "CONTROL = Key, Key" line per control.
+func (ct *Controls) Save(fileName string) {
+	//------------------------------------------------------
+	f, err := os.Create(fileName)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for c, keys := range ct.keys {
+		fmt.Fprintf(w, "%s = %s\n", Control(c), keyNames(keys))
+	}
+	if err := w.Flush(); err != nil {
+		log.Println(err)
+	}
+}
+
+// ConfigLine_t sets the value of a "NAME = value" line, both trimmed. The
+// error returned is logged with the line number.
+type ConfigLine_t func(name, value string) error
+
+// LoadConfig reads the "NAME = value" lines of Controls.cfg and
+// Settings.cfg. Empty lines and lines starting with # are skipped; a
+// missing file is not an error.
+func LoadConfig(fileName string, setLine ConfigLine_t) {
+	//------------------------------------------------------
+	f, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		log.Println(err)
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for iLine := 1; scanner.Scan(); iLine++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, "=")
+		if err := setLine(strings.TrimSpace(name), strings.TrimSpace(value)); err != nil {
+			log.Printf("%s:%d: %v", fileName, iLine, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println(err)
+	}
+}
+
+// Load reads the bindings saved by Save. Controls missing from the file
+// keep their current keys; unknown controls or keys are skipped.
+func (ct *Controls) Load(fileName string) {
+	//------------------------------------------------------
+	LoadConfig(fileName, func(name, value string) error {
+		c, fFound := controlOf(name)
+		if !fFound {
+			return errors.New("unknown control")
+		}
+		var keys []pixelgl.Button
+		var unknownKeys []string
+		for _, keyName := range strings.Split(value, ",") {
+			if b, ok := ButtonOf(strings.TrimSpace(keyName)); ok {
+				keys = append(keys, b)
+			} else {
+				unknownKeys = append(unknownKeys, strings.TrimSpace(keyName))
+			}
+		}
+		if len(keys) > 0 {
+			ct.keys[c] = keys
+		}
+		if len(unknownKeys) > 0 {
+			return fmt.Errorf("unknown key %q", strings.Join(unknownKeys, ", "))
+		}
+		return nil
+	})
+}
+
+func controlOf(name string) (Control, bool) {
+	for c, s := range controlNames {
+		if strings.EqualFold(s, name) {
+			return Control(c), true
+		}
+	}
+	return 0, false
+}
+
+// Rebinding screen: MENU_UP/MENU_DOWN select a control, VALIDATE waits for
+// its new key, DELETE restores its default keys and BACK saves and leaves.
+
+const NB_CONTROLS_SHOWN = 15
+
+// The controls screen goes back to the screen it was opened from.
+var (
+	idControl         Control
+	fBindingKey       bool
+	backMode          GameMode
+	backProcessEvents ProcessEvents_t
+	backDrawMode      DrawMode_t
+)
+
+func StartControlsMode() {
+	//--------------------------------------------------
+	backMode, backProcessEvents, backDrawMode = game.curMode, processEvents, drawCurMode
+	idControl = 0
+	fBindingKey = false
+	game.curMode = CONTROLS
+	processEvents = ProcessEventsControls
+	drawCurMode = DrawControlsMode
+}
+
+func ProcessEventsControls(win pixelgl.Window) bool {
+
+	if fBindingKey {
+		//-- The next key pressed is the new one, BACK gives up
+		if controls.JustPressed(&win, CTL_BACK) {
+			fBindingKey = false
+			return true
+		}
+		for b := pixelgl.KeySpace; b <= pixelgl.KeyLast; b++ {
+			if win.JustPressed(b) {
+				controls.Bind(idControl, b)
+				fBindingKey = false
+				break
+			}
+		}
+		return true
+	}
+
+	if controls.JustPressed(&win, CTL_MENU_UP) {
+		idControl = (idControl + NB_CONTROLS - 1) % NB_CONTROLS
+	} else if controls.JustPressed(&win, CTL_MENU_DOWN) {
+		idControl = (idControl + 1) % NB_CONTROLS
+	} else if controls.JustPressed(&win, CTL_VALIDATE) {
+		fBindingKey = true
+	} else if controls.JustPressed(&win, CTL_DELETE) {
+		controls.keys[idControl] = append([]pixelgl.Button(nil), defaultKeys[idControl]...)
+	} else if controls.JustPressed(&win, CTL_BACK) {
+		controls.Save(CONTROLS_FILE)
+		game.curMode, processEvents, drawCurMode = backMode, backProcessEvents, backDrawMode
+	}
+	return true
+}
+
+func keyNames(keys []pixelgl.Button) string {
+	names := make([]string, len(keys))
+	for i, b := range keys {
+		names[i] = b.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// KeyName is the name of the first key of c, for on-screen hints.
+func (ct *Controls) KeyName(c Control) string {
+	return ct.keys[c][0].String()
+}
+
+func DrawControlsMode(win pixel.Target) {
+
+	oy := float64(WIN_HEIGHT - TOP - 2*cellSize)
+	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
+	txt := text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Gold
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "CONTROLS")
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	//-- Scroll to keep the selected control in view
+	first := min(max(int(idControl)-NB_CONTROLS_SHOWN/2, 0), int(NB_CONTROLS)-NB_CONTROLS_SHOWN)
+	oy -= float64(cellSize / 2)
+	x1 := float64(LEFT + 4)
+	x3 := float64(LEFT + NB_COLUMNS*cellSize - 4)
+	for c := Control(first); c < Control(first+NB_CONTROLS_SHOWN); c++ {
+		lineColor := colornames.Gold
+		keys := keyNames(controls.Keys(c))
+		if c == idControl {
+			lineColor = colornames.Orange
+			if fBindingKey {
+				keys = "..."
+			}
+		}
+		oy -= float64(cellSize)
+		txt = text.New(pixel.V(x1, oy), atlas)
+		txt.Color = lineColor
+		fmt.Fprintf(txt, "%s", c)
+		txt.Draw(win, pixel.IM)
+		txt = text.New(pixel.V(x3, oy), atlas)
+		txt.Color = lineColor
+		fmt.Fprintf(txt, "%s", keys)
+		txt.Draw(win, pixel.IM.Moved(pixel.V(-txt.Bounds().W(), 0)))
+	}
+
+	oy -= float64(3 * cellSize / 2)
+	txt = text.New(pixel.V(x1, oy), atlas)
+	txt.Color = colornames.Gold
+	fmt.Fprintf(txt, "%s : bind  %s : default", controls.KeyName(CTL_VALIDATE), controls.KeyName(CTL_DELETE))
+	txt.Draw(win, pixel.IM)
+
+}
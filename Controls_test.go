@@ -0,0 +1,134 @@
+package main
+
+import (
+	"testing"
+
+	"pixel_tetris/engine"
+
+	"github.com/gopxl/pixel/pixelgl"
+)
+
+// fakeButtons is a synthetic keyboard and set of gamepads.
+type fakeButtons struct {
+	pressed, justPressed, justReleased map[pixelgl.Button]bool
+	pads                               map[pixelgl.Joystick]*fakePad
+}
+
+type fakePad struct {
+	pressed, justPressed, justReleased map[pixelgl.GamepadButton]bool
+}
+
+func fakeButtonsNew() *fakeButtons {
+	return &fakeButtons{
+		pressed:      map[pixelgl.Button]bool{},
+		justPressed:  map[pixelgl.Button]bool{},
+		justReleased: map[pixelgl.Button]bool{},
+		pads:         map[pixelgl.Joystick]*fakePad{},
+	}
+}
+
+func (fb *fakeButtons) pad(js pixelgl.Joystick) *fakePad {
+	p := &fakePad{
+		pressed:      map[pixelgl.GamepadButton]bool{},
+		justPressed:  map[pixelgl.GamepadButton]bool{},
+		justReleased: map[pixelgl.GamepadButton]bool{},
+	}
+	fb.pads[js] = p
+	return p
+}
+
+func (fb *fakeButtons) Pressed(b pixelgl.Button) bool      { return fb.pressed[b] }
+func (fb *fakeButtons) JustPressed(b pixelgl.Button) bool  { return fb.justPressed[b] }
+func (fb *fakeButtons) JustReleased(b pixelgl.Button) bool { return fb.justReleased[b] }
+
+func (fb *fakeButtons) JoystickPresent(js pixelgl.Joystick) bool {
+	return fb.pads[js] != nil
+}
+
+func (fb *fakeButtons) JoystickPressed(js pixelgl.Joystick, b pixelgl.GamepadButton) bool {
+	return fb.pads[js] != nil && fb.pads[js].pressed[b]
+}
+
+func (fb *fakeButtons) JoystickJustPressed(js pixelgl.Joystick, b pixelgl.GamepadButton) bool {
+	return fb.pads[js] != nil && fb.pads[js].justPressed[b]
+}
+
+func (fb *fakeButtons) JoystickJustReleased(js pixelgl.Joystick, b pixelgl.GamepadButton) bool {
+	return fb.pads[js] != nil && fb.pads[js].justReleased[b]
+}
+
+func TestGamepadPlayInputs(t *testing.T) {
+	for _, tc := range []struct {
+		button pixelgl.GamepadButton
+		action engine.Action
+	}{
+		{pixelgl.ButtonDpadLeft, engine.MOVE_LEFT},
+		{pixelgl.ButtonDpadRight, engine.MOVE_RIGHT},
+		{pixelgl.ButtonDpadDown, engine.FAST_DOWN},
+		{pixelgl.ButtonDpadUp, engine.HARD_DROP},
+		{pixelgl.ButtonA, engine.ROTATE_RIGHT},
+		{pixelgl.ButtonB, engine.ROTATE_LEFT},
+		{pixelgl.ButtonY, engine.ROTATE_180},
+		{pixelgl.ButtonLeftBumper, engine.HOLD},
+	} {
+		fb := fakeButtonsNew()
+		fb.pad(pixelgl.Joystick2).justPressed[tc.button] = true
+		inputs := ControlsNew().PlayInputs(fb, nil)
+		if len(inputs) != 1 || inputs[0] != (engine.InputEvent{Action: tc.action, Pressed: true}) {
+			t.Errorf("button %d: inputs %v, want action %d pressed", tc.button, inputs, tc.action)
+		}
+	}
+}
+
+func TestGamepadRelease(t *testing.T) {
+	fb := fakeButtonsNew()
+	fb.pad(pixelgl.Joystick1).justReleased[pixelgl.ButtonDpadLeft] = true
+	inputs := ControlsNew().PlayInputs(fb, nil)
+	if len(inputs) != 1 || inputs[0] != (engine.InputEvent{Action: engine.MOVE_LEFT, Pressed: false}) {
+		t.Errorf("inputs %v, want move left released", inputs)
+	}
+}
+
+func TestGamepadMenus(t *testing.T) {
+	ct := ControlsNew()
+	for _, tc := range []struct {
+		button pixelgl.GamepadButton
+		ctl    Control
+	}{
+		{pixelgl.ButtonStart, CTL_PAUSE},
+		{pixelgl.ButtonDpadLeft, CTL_MENU_LEFT},
+		{pixelgl.ButtonDpadRight, CTL_MENU_RIGHT},
+		{pixelgl.ButtonDpadUp, CTL_MENU_UP},
+		{pixelgl.ButtonDpadDown, CTL_MENU_DOWN},
+		{pixelgl.ButtonA, CTL_START},
+		{pixelgl.ButtonStart, CTL_VALIDATE},
+		{pixelgl.ButtonBack, CTL_BACK},
+		{pixelgl.ButtonX, CTL_DELETE},
+		{pixelgl.ButtonY, CTL_HIGHSCORES},
+	} {
+		fb := fakeButtonsNew()
+		fb.pad(pixelgl.Joystick1).justPressed[tc.button] = true
+		if !ct.JustPressed(fb, tc.ctl) {
+			t.Errorf("button %d does not trigger %s", tc.button, tc.ctl)
+		}
+	}
+}
+
+func TestGamepadAbsent(t *testing.T) {
+	fb := fakeButtonsNew()
+	p := fb.pad(pixelgl.Joystick1)
+	p.justPressed[pixelgl.ButtonA] = true
+	delete(fb.pads, pixelgl.Joystick1)
+	if inputs := ControlsNew().PlayInputs(fb, nil); len(inputs) != 0 {
+		t.Errorf("disconnected pad gave inputs %v", inputs)
+	}
+}
+
+func TestKeyboardStillMapped(t *testing.T) {
+	fb := fakeButtonsNew()
+	fb.pad(pixelgl.Joystick1)
+	fb.pressed[pixelgl.KeyDown] = true
+	if !ControlsNew().Pressed(fb, CTL_SOFT_DROP) {
+		t.Error("keyboard soft drop not seen with a pad connected")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"pixel_tetris/engine"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+// Time left idle on the standby screen before the demo starts.
+const DEMO_IDLE = 20 * time.Second
+
+// The demo is a game of its own played by the bot, so that it never
+// touches the score nor the high scores of the player.
+var (
+	demoGame    *engine.Game
+	demoBot     *engine.Bot
+	idleElapsed time.Duration
+)
+
+func StartDemo() {
+	//--------------------------------------------------
+	demoGame = engine.GameNew(NewSeed())
+	demoGame.RandomizerType = game.RandomizerType
+	demoGame.Start()
+	demoBot = engine.BotNew()
+	demoBot.Delay = BOT_DELAY
+}
+
+func StopDemo() {
+	//--------------------------------------------------
+	demoGame = nil
+	demoBot = nil
+	idleElapsed = 0
+}
+
+// UpdateDemo counts the idle time on the standby screen and plays the demo
+// once it is started, with a new one after each game over.
+func UpdateDemo(dt time.Duration) {
+	//--------------------------------------------------
+	if demoGame == nil {
+		idleElapsed += dt
+		if idleElapsed >= DEMO_IDLE {
+			StartDemo()
+		}
+		return
+	}
+	demoGame.Update(dt, demoBot.Inputs(demoGame, dt))
+	if demoGame.Mode != engine.PLAY {
+		StartDemo()
+	}
+}
+
+// DrawDemo draws the demo game on the board, under the standby text.
+func DrawDemo(win pixel.Target) {
+
+	DrawCells(win, demoGame.Board)
+	if te := demoGame.CurTetromino; te != nil {
+		DrawTetromino(win, te, te.X, demoGame.RenderY())
+	}
+
+	oy := float64(WIN_HEIGHT - TOP - cellSize)
+	txt := text.New(pixel.V(0, 0), atlas)
+	txt.Color = colornames.Orange
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "DEMO")
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"pixel_tetris/engine"
+)
+
+const (
+	HIGHSCORES_FILE        = "HighScores.json"
+	LEGACY_HIGHSCORES_FILE = "HighScores.txt"
+	HIGHSCORES_VERSION     = 1
+	NB_HIGHSCORES          = 10
+	ULTRA_2MIN             = "ULTRA 2MIN"
+	ULTRA_3MIN             = "ULTRA 3MIN"
+)
+
+type HightScore struct {
+	Name     string        `json:"name"`
+	Score    int           `json:"score"`
+	Date     time.Time     `json:"date"`
+	Lines    int           `json:"lines"`
+	Level    int           `json:"level"`
+	Duration time.Duration `json:"duration"`
+	Seed     int64         `json:"seed"`
+	Mode     string        `json:"mode"`
+}
+
+// An empty slot of a leaderboard has no mode.
+func (h HightScore) IsEmpty() bool {
+	return h.Mode == ""
+}
+
+// Ranked tells whether h may enter a leaderboard: a SPRINT only counts once
+// all its lines are cleared.
+func (h HightScore) Ranked() bool {
+	if h.Mode == engine.SPRINT.String() {
+		return h.Lines >= engine.SPRINT_LINES
+	}
+	return h.Score > 0
+}
+
+// Rank_t tells whether a ranks before b. Neither of them is empty.
+type Rank_t func(a, b HightScore) bool
+
+type FormatScore_t func(h HightScore) string
+
+func RankByScore(a, b HightScore) bool {
+	return a.Score > b.Score
+}
+
+func RankByTime(a, b HightScore) bool {
+	return a.Duration < b.Duration
+}
+
+func FormatScore(h HightScore) string {
+	return fmt.Sprintf("%06d", h.Score)
+}
+
+func FormatTime(h HightScore) string {
+	if h.IsEmpty() {
+		return "--:--.---"
+	}
+	return FormatDuration(h.Duration)
+}
+
+// FormatDuration shows d to the millisecond. Game times are whole ticks of
+// engine.TICK, so they are only exact to about 8ms.
+func FormatDuration(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+type Leaderboard struct {
+	mode   string
+	rank   Rank_t
+	format FormatScore_t
+	scores []HightScore
+}
+
+func LeaderboardNew(mode string, rank Rank_t, format FormatScore_t) *Leaderboard {
+	lb := &Leaderboard{mode: mode, rank: rank, format: format}
+	lb.setScores(nil)
+	return lb
+}
+
+func LeaderboardsNew() []*Leaderboard {
+	return []*Leaderboard{
+		LeaderboardNew(engine.MARATHON.String(), RankByScore, FormatScore),
+		LeaderboardNew(engine.SPRINT.String(), RankByTime, FormatTime),
+		LeaderboardNew(ULTRA_2MIN, RankByScore, FormatScore),
+		LeaderboardNew(ULTRA_3MIN, RankByScore, FormatScore),
+	}
+}
+
+func (lb *Leaderboard) before(a, b HightScore) bool {
+	if b.IsEmpty() {
+		return !a.IsEmpty()
+	}
+	return !a.IsEmpty() && lb.rank(a, b)
+}
+
+func (lb *Leaderboard) setScores(scores []HightScore) {
+	//------------------------------------------------------
+	lb.scores = make([]HightScore, 0, NB_HIGHSCORES)
+	for _, h := range scores {
+		if id := lb.IsHightScore(h); id >= 0 {
+			lb.Insert(id, h)
+		}
+	}
+	for len(lb.scores) < NB_HIGHSCORES {
+		lb.scores = append(lb.scores, HightScore{Name: "--------"})
+	}
+}
+
+func (lb *Leaderboard) IsHightScore(h HightScore) int {
+	//--------------------------------------------------
+	for i, v := range lb.scores {
+		if lb.before(h, v) {
+			return i
+		}
+	}
+	if len(lb.scores) < NB_HIGHSCORES {
+		return len(lb.scores)
+	}
+	return -1
+}
+
+func (lb *Leaderboard) Insert(id int, h HightScore) {
+	//--------------------------------------------------
+	lb.scores = append(lb.scores, HightScore{})
+	copy(lb.scores[id+1:], lb.scores[id:])
+	lb.scores[id] = h
+	if len(lb.scores) > NB_HIGHSCORES {
+		lb.scores = lb.scores[:NB_HIGHSCORES]
+	}
+}
+
+// Entries are kept raw so that a bad one can be skipped on its own.
+type HighScoresStore struct {
+	Version int               `json:"version"`
+	Scores  []json.RawMessage `json:"scores"`
+}
+
+func (ga *Game) NewHighScore() HightScore {
+	//--------------------------------------------------
+	return HightScore{
+		Name:     ga.userName,
+		Score:    ga.CurScore,
+		Date:     time.Now(),
+		Lines:    ga.Lines,
+		Level:    ga.Level,
+		Duration: ga.PlayTime(),
+		Seed:     ga.Seed,
+		Mode:     ga.ModeName(),
+	}
+}
+
+// ModeName names the leaderboard of the current game type. Each ULTRA
+// length has its own.
+func (ga *Game) ModeName() string {
+	//--------------------------------------------------
+	if ga.Type == engine.ULTRA {
+		return fmt.Sprintf("ULTRA %dMIN", int(ga.UltraTime.Minutes()))
+	}
+	return ga.Type.String()
+}
+
+func (ga *Game) Leaderboard(mode string) (int, *Leaderboard) {
+	//--------------------------------------------------
+	for i, lb := range ga.leaderboards {
+		if lb.mode == mode {
+			return i, lb
+		}
+	}
+	return -1, nil
+}
+
+func (ga *Game) SaveHighScores(fileName string) {
+	//------------------------------------------------------
+	store := HighScoresStore{Version: HIGHSCORES_VERSION}
+	add := func(h HightScore) {
+		if h.IsEmpty() {
+			return
+		}
+		if h.Name == "" {
+			h.Name = "XXXX"
+		}
+		raw, err := json.Marshal(h)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		store.Scores = append(store.Scores, raw)
+	}
+	for _, lb := range ga.leaderboards {
+		for _, h := range lb.scores {
+			add(h)
+		}
+	}
+	for _, h := range ga.otherScores {
+		add(h)
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		log.Println(err)
+	}
+
+}
+
+func (ga *Game) LoadHighScores(fileName string) {
+	//------------------------------------------------------
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		//-- First run with the JSON store
+		if ga.LoadLegacyHighScores(LEGACY_HIGHSCORES_FILE) {
+			ga.SaveHighScores(fileName)
+		}
+		return
+	} else if err != nil {
+		log.Println(err)
+		return
+	}
+
+	var store HighScoresStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		log.Printf("%s: %v", fileName, err)
+		return
+	}
+	if store.Version > HIGHSCORES_VERSION {
+		log.Printf("%s: newer version %d, loading known fields only", fileName, store.Version)
+	}
+
+	var scores []HightScore
+	for i, raw := range store.Scores {
+		var h HightScore
+		if err := json.Unmarshal(raw, &h); err != nil {
+			log.Printf("%s: skip entry %d: %v", fileName, i, err)
+			continue
+		}
+		scores = append(scores, h)
+	}
+	ga.setHighScores(scores)
+
+}
+
+// LoadLegacyHighScores reads the former "name score" text file. Lines that
+// do not end with a positive score are skipped.
+func (ga *Game) LoadLegacyHighScores(fileName string) bool {
+	//------------------------------------------------------
+	f, err := os.Open(fileName)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	var scores []HightScore
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		wordBreakDown := strings.Fields(scanner.Text())
+		if len(wordBreakDown) < 2 {
+			continue
+		}
+		last := len(wordBreakDown) - 1
+		val, err := strconv.Atoi(wordBreakDown[last])
+		if err != nil || val <= 0 {
+			continue
+		}
+		name := strings.Join(wordBreakDown[:last], " ")
+		scores = append(scores, HightScore{Name: name, Score: val, Mode: engine.MARATHON.String()})
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println(err)
+	}
+
+	ga.setHighScores(scores)
+	return true
+}
+
+// setHighScores dispatches scores to the leaderboard of their mode. Scores
+// of modes unknown to this version are kept to be saved back untouched.
+func (ga *Game) setHighScores(scores []HightScore) {
+	//------------------------------------------------------
+	byMode := map[string][]HightScore{}
+	ga.otherScores = nil
+	for _, h := range scores {
+		if h.IsEmpty() {
+			continue
+		}
+		if _, lb := ga.Leaderboard(h.Mode); lb == nil {
+			ga.otherScores = append(ga.otherScores, h)
+			continue
+		}
+		byMode[h.Mode] = append(byMode[h.Mode], h)
+	}
+	for _, lb := range ga.leaderboards {
+		lb.setScores(byMode[lb.mode])
+	}
+}
+
+// IsHightScore returns the rank h would get on the leaderboard of its
+// mode, or -1.
+func (ga *Game) IsHightScore(h HightScore) int {
+	//--------------------------------------------------
+	_, lb := ga.Leaderboard(h.Mode)
+	if lb == nil || !h.Ranked() {
+		return -1
+	}
+	return lb.IsHightScore(h)
+}
+
+func (ga *Game) InsertHightScore(id int, h HightScore) {
+	//--------------------------------------------------
+	idBoard, lb := ga.Leaderboard(h.Mode)
+	lb.Insert(id, h)
+	ga.idBoard = idBoard
+	ga.idHighScoreBoard = idBoard
+	ga.idHighScore = id
+	ga.userName = h.Name
+
+}
+
+// HighScoreEntry is the leaderboard entry being named, if any.
+func (ga *Game) HighScoreEntry() *HightScore {
+	//--------------------------------------------------
+	if ga.idHighScore < 0 {
+		return nil
+	}
+	return &ga.leaderboards[ga.idHighScoreBoard].scores[ga.idHighScore]
+}
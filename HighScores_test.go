@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pixel_tetris/engine"
+)
+
+// inTempDir runs the test in a directory of its own, where the high score
+// files are looked for.
+func inTempDir(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+}
+
+func writeFile(t *testing.T, fileName, content string) {
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func marathonScores(ga *Game) []HightScore {
+	_, lb := ga.Leaderboard(engine.MARATHON.String())
+	var scores []HightScore
+	for _, h := range lb.scores {
+		if !h.IsEmpty() {
+			scores = append(scores, h)
+		}
+	}
+	return scores
+}
+
+func TestLegacyHighScoresMigration(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, LEGACY_HIGHSCORES_FILE, "BOB SMITH 800\nALICE 1200\n\njunk\nNEG -5\nCAROL abc\nDAVE 0\n")
+
+	ga := GameNew()
+	ga.LoadHighScores(HIGHSCORES_FILE)
+	scores := marathonScores(ga)
+	if len(scores) != 2 || scores[0].Name != "ALICE" || scores[0].Score != 1200 ||
+		scores[1].Name != "BOB SMITH" || scores[1].Score != 800 {
+		t.Fatalf("migrated %+v", scores)
+	}
+	if _, err := os.Stat(HIGHSCORES_FILE); err != nil {
+		t.Fatalf("JSON store not written: %v", err)
+	}
+
+	//-- The legacy file is only read once the JSON store is missing
+	writeFile(t, LEGACY_HIGHSCORES_FILE, "EVE 5000\n")
+	ga = GameNew()
+	ga.LoadHighScores(HIGHSCORES_FILE)
+	if got := marathonScores(ga); len(got) != 2 || got[0].Name != "ALICE" {
+		t.Errorf("reloaded %+v", got)
+	}
+}
+
+func TestHighScoresMissing(t *testing.T) {
+	inTempDir(t)
+	ga := GameNew()
+	ga.LoadHighScores(HIGHSCORES_FILE)
+	if scores := marathonScores(ga); len(scores) != 0 {
+		t.Errorf("loaded %+v from nothing", scores)
+	}
+	if _, err := os.Stat(HIGHSCORES_FILE); !os.IsNotExist(err) {
+		t.Errorf("JSON store written without legacy scores")
+	}
+}
+
+func TestHighScoresMalformed(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, HIGHSCORES_FILE, `{
+  "version": 2,
+  "future": true,
+  "scores": [
+    {"name": "ALICE", "score": 1200, "mode": "MARATHON", "stars": 3},
+    {"name": "BOB", "score": "800", "mode": "MARATHON"},
+    {"name": "CAROL", "score": 900},
+    {"score": 700, "mode": "MARATHON"},
+    {"name": "DAVE", "lines": 40, "duration": 61000000000, "mode": "SPRINT"},
+    {"name": "EVE", "score": 100, "mode": "ZEN"},
+    [1, 2]
+  ]
+}`)
+
+	ga := GameNew()
+	ga.LoadHighScores(HIGHSCORES_FILE)
+	scores := marathonScores(ga)
+	if len(scores) != 2 || scores[0].Name != "ALICE" || scores[1].Name != "" || scores[1].Score != 700 {
+		t.Errorf("marathon %+v", scores)
+	}
+	_, lb := ga.Leaderboard(engine.SPRINT.String())
+	if h := lb.scores[0]; h.Name != "DAVE" || h.Score != 0 || h.Duration.Seconds() != 61 {
+		t.Errorf("sprint %+v", h)
+	}
+	if len(ga.otherScores) != 1 || ga.otherScores[0].Name != "EVE" {
+		t.Errorf("other modes %+v", ga.otherScores)
+	}
+
+	//-- Scores of unknown modes are saved back
+	fileName := filepath.Join(t.TempDir(), HIGHSCORES_FILE)
+	ga.SaveHighScores(fileName)
+	ga = GameNew()
+	ga.LoadHighScores(fileName)
+	if len(ga.otherScores) != 1 || len(marathonScores(ga)) != 2 {
+		t.Errorf("saved back %+v, %+v", marathonScores(ga), ga.otherScores)
+	}
+}
+
+func TestHighScoresTruncated(t *testing.T) {
+	inTempDir(t)
+	content := `{"version": 1, "scores": [{"name": "ALICE", "score": 1200, "mode": "MARATHON"}, {"name": "BO`
+	writeFile(t, HIGHSCORES_FILE, content)
+	writeFile(t, LEGACY_HIGHSCORES_FILE, "EVE 5000\n")
+
+	ga := GameNew()
+	ga.LoadHighScores(HIGHSCORES_FILE)
+	if scores := marathonScores(ga); len(scores) != 0 {
+		t.Errorf("loaded %+v from a truncated file", scores)
+	}
+	if data, err := os.ReadFile(HIGHSCORES_FILE); err != nil || string(data) != content {
+		t.Errorf("truncated file overwritten")
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"pixel_tetris/engine"
+
+	"github.com/faiface/beep/speaker"
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/pixelgl"
+	"github.com/gopxl/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+const SETTINGS_FILE = "Settings.cfg"
+
+// Volumes go from 0, silent, to MAX_VOLUME, each step is 3dB.
+const MAX_VOLUME = 10
+
+// Settings are the preferences of the player, kept between launches. DAS,
+// ARR and the randomizer are given to the games started after they change.
+type Settings struct {
+	MusicVolume int
+	SfxVolume   int
+	Ghost       bool
+	NbPreviews  int
+	DAS         time.Duration
+	ARR         time.Duration
+	Randomizer  engine.RandomizerType
+	Theme       int
+}
+
+func SettingsDefault() Settings {
+	cfg := engine.ConfigDefault()
+	return Settings{
+		MusicVolume: 4,
+		SfxVolume:   6,
+		Ghost:       true,
+		NbPreviews:  NB_PREVIEWS,
+		DAS:         cfg.DAS,
+		ARR:         cfg.ARR,
+		Randomizer:  cfg.RandomizerType,
+	}
+}
+
+var settings = SettingsDefault()
+
+// option is a line of the options screen, saved under its name. change
+// steps its value down (dir < 0) or up.
+type option struct {
+	name   string
+	value  func() string
+	parse  func(s string) bool
+	change func(dir int)
+}
+
+func intOption(name string, v *int, lo, hi int) option {
+	return option{
+		name:  name,
+		value: func() string { return strconv.Itoa(*v) },
+		parse: func(s string) bool {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < lo || n > hi {
+				return false
+			}
+			*v = n
+			return true
+		},
+		change: func(dir int) { *v = min(max(*v+dir, lo), hi) },
+	}
+}
+
+func durationOption(name string, v *time.Duration, lo, hi, step time.Duration) option {
+	return option{
+		name:  name,
+		value: func() string { return fmt.Sprintf("%dms", v.Milliseconds()) },
+		parse: func(s string) bool {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < lo || d > hi {
+				return false
+			}
+			*v = d
+			return true
+		},
+		change: func(dir int) { *v = min(max(*v+time.Duration(dir)*step, lo), hi) },
+	}
+}
+
+func boolOption(name string, v *bool) option {
+	return option{
+		name: name,
+		value: func() string {
+			if *v {
+				return "ON"
+			}
+			return "OFF"
+		},
+		parse: func(s string) bool {
+			switch strings.ToUpper(s) {
+			case "ON":
+				*v = true
+			case "OFF":
+				*v = false
+			default:
+				return false
+			}
+			return true
+		},
+		change: func(dir int) { *v = !*v },
+	}
+}
+
+func themeOption(name string, v *int) option {
+	return option{
+		name:  name,
+		value: func() string { return themes[*v].name },
+		parse: func(s string) bool {
+			for id, th := range themes {
+				if strings.EqualFold(th.name, s) {
+					*v = id
+					return true
+				}
+			}
+			return false
+		},
+		change: func(dir int) { *v = (*v + len(themes) + dir) % len(themes) },
+	}
+}
+
+func randomizerOption(name string, v *engine.RandomizerType) option {
+	return option{
+		name:  name,
+		value: func() string { return v.String() },
+		parse: func(s string) bool {
+			rt, ok := engine.RandomizerTypeOf(s)
+			if ok {
+				*v = rt
+			}
+			return ok
+		},
+		change: func(dir int) { *v = (*v + engine.NB_RANDOMIZERS + engine.RandomizerType(dir)) % engine.NB_RANDOMIZERS },
+	}
+}
+
+var options = []option{
+	intOption("MUSIC_VOLUME", &settings.MusicVolume, 0, MAX_VOLUME),
+	intOption("SFX_VOLUME", &settings.SfxVolume, 0, MAX_VOLUME),
+	boolOption("GHOST", &settings.Ghost),
+	intOption("PREVIEWS", &settings.NbPreviews, 1, engine.PREVIEW_SIZE),
+	durationOption("DAS", &settings.DAS, 0, 500*time.Millisecond, 10*time.Millisecond),
+	durationOption("ARR", &settings.ARR, 0, 200*time.Millisecond, 10*time.Millisecond),
+	randomizerOption("RANDOMIZER", &settings.Randomizer),
+	themeOption("THEME", &settings.Theme),
+}
+
+// volumeOf is the effects.Volume exponent, base 2, of a volume level.
+func volumeOf(level int) (volume float64, silent bool) {
+	return float64(level-MAX_VOLUME) / 2, level == 0
+}
+
+// ApplySettings puts the settings in effect, those of StartGame aside.
+func ApplySettings() {
+	//--------------------------------------------------
+	speaker.Lock()
+	musicVolume.Volume, musicVolume.Silent = volumeOf(settings.MusicVolume)
+	speaker.Unlock()
+	game.fShowGhost = settings.Ghost
+	game.nbPreviews = settings.NbPreviews
+	SetTheme(settings.Theme)
+}
+
+// SaveSettings writes a "NAME = value" line per option.
+func SaveSettings(fileName string) {
+	//------------------------------------------------------
+	f, err := os.Create(fileName)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, op := range options {
+		fmt.Fprintf(w, "%s = %s\n", op.name, op.value())
+	}
+	if err := w.Flush(); err != nil {
+		log.Println(err)
+	}
+}
+
+// LoadSettings reads the options saved by SaveSettings. Options missing
+// from the file keep their value; unknown or invalid ones are skipped.
+func LoadSettings(fileName string) {
+	//------------------------------------------------------
+	LoadConfig(fileName, func(name, value string) error {
+		for _, op := range options {
+			if strings.EqualFold(op.name, name) {
+				if !op.parse(value) {
+					return fmt.Errorf("invalid value %q", value)
+				}
+				return nil
+			}
+		}
+		return errors.New("unknown option")
+	})
+}
+
+// Options screen: MENU_UP/MENU_DOWN select a line, MENU_LEFT/MENU_RIGHT
+// change its value at once, VALIDATE on the last line opens the controls
+// screen and BACK saves and leaves.
+
+var (
+	idOption                 int
+	optionsBackMode          GameMode
+	optionsBackProcessEvents ProcessEvents_t
+	optionsBackDrawMode      DrawMode_t
+)
+
+func StartOptionsMode() {
+	//--------------------------------------------------
+	optionsBackMode, optionsBackProcessEvents, optionsBackDrawMode = game.curMode, processEvents, drawCurMode
+	idOption = 0
+	game.curMode = OPTIONS
+	processEvents = ProcessEventsOptions
+	drawCurMode = DrawOptionsMode
+}
+
+func ProcessEventsOptions(win pixelgl.Window) bool {
+
+	//-- The options and a last line for the key bindings
+	nbLines := len(options) + 1
+	if controls.JustPressed(&win, CTL_MENU_UP) {
+		idOption = (idOption + nbLines - 1) % nbLines
+	} else if controls.JustPressed(&win, CTL_MENU_DOWN) {
+		idOption = (idOption + 1) % nbLines
+	} else if controls.JustPressed(&win, CTL_MENU_LEFT) && idOption < len(options) {
+		options[idOption].change(-1)
+		ApplySettings()
+	} else if controls.JustPressed(&win, CTL_MENU_RIGHT) && idOption < len(options) {
+		options[idOption].change(1)
+		ApplySettings()
+	} else if controls.JustPressed(&win, CTL_VALIDATE) && idOption == len(options) {
+		StartControlsMode()
+	} else if controls.JustPressed(&win, CTL_BACK) {
+		SaveSettings(SETTINGS_FILE)
+		game.curMode, processEvents, drawCurMode = optionsBackMode, optionsBackProcessEvents, optionsBackDrawMode
+	}
+	return true
+}
+
+func DrawOptionsMode(win pixel.Target) {
+
+	oy := float64(WIN_HEIGHT - TOP - 2*cellSize)
+	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
+	txt := text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Gold
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "OPTIONS")
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	oy -= float64(cellSize / 2)
+	x1 := float64(LEFT + 4)
+	x3 := float64(LEFT + NB_COLUMNS*cellSize - 4)
+	for i := 0; i <= len(options); i++ {
+		lineColor := colornames.Gold
+		if i == idOption {
+			lineColor = colornames.Orange
+		}
+		name, value := "KEY BINDINGS", "..."
+		if i < len(options) {
+			name = strings.ReplaceAll(options[i].name, "_", " ")
+			value = options[i].value()
+			if i == idOption {
+				value = "< " + value + " >"
+			}
+		}
+		oy -= float64(cellSize + 4)
+		txt = text.New(pixel.V(x1, oy), atlas)
+		txt.Color = lineColor
+		fmt.Fprintf(txt, "%s", name)
+		txt.Draw(win, pixel.IM)
+		txt = text.New(pixel.V(x3, oy), atlas)
+		txt.Color = lineColor
+		fmt.Fprintf(txt, "%s", value)
+		txt.Draw(win, pixel.IM.Moved(pixel.V(-txt.Bounds().W(), 0)))
+	}
+
+	oy -= float64(3 * cellSize / 2)
+	txt = text.New(pixel.V(x1, oy), atlas)
+	txt.Color = colornames.Gold
+	fmt.Fprintf(txt, "%s : save and leave", controls.KeyName(CTL_BACK))
+	txt.Draw(win, pixel.IM)
+
+}
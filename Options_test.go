@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pixel_tetris/engine"
+)
+
+func TestSettingsSaveLoad(t *testing.T) {
+	defer func() { settings = SettingsDefault() }()
+
+	want := Settings{
+		MusicVolume: 0,
+		SfxVolume:   MAX_VOLUME,
+		Ghost:       false,
+		NbPreviews:  6,
+		DAS:         120 * time.Millisecond,
+		ARR:         0,
+		Randomizer:  engine.TGM_HISTORY,
+		Theme:       len(themes) - 1,
+	}
+	settings = want
+	fileName := filepath.Join(t.TempDir(), SETTINGS_FILE)
+	SaveSettings(fileName)
+
+	settings = SettingsDefault()
+	LoadSettings(fileName)
+	if settings != want {
+		t.Errorf("loaded %+v, want %+v", settings, want)
+	}
+}
+
+func TestSettingsInvalid(t *testing.T) {
+	defer func() { settings = SettingsDefault() }()
+
+	fileName := filepath.Join(t.TempDir(), SETTINGS_FILE)
+	content := "MUSIC_VOLUME = 99\nPREVIEWS = 2\nTHEME = NONE\nUNKNOWN = 1\nDAS = 1h\nRANDOMIZER = 3-BAG\n"
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings = SettingsDefault()
+	LoadSettings(fileName)
+
+	want := SettingsDefault()
+	want.NbPreviews = 2
+	if settings != want {
+		t.Errorf("loaded %+v, want %+v", settings, want)
+	}
+}
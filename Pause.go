@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"pixel_tetris/engine"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/pixelgl"
+	"github.com/gopxl/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+const (
+	PAUSE_RESUME = iota
+	PAUSE_RESTART
+	PAUSE_OPTIONS
+	PAUSE_QUIT
+	NB_PAUSE_ITEMS
+)
+
+var pauseItems = [NB_PAUSE_ITEMS]string{"RESUME", "RESTART", "OPTIONS", "QUIT TO TITLE"}
+
+// The board stays hidden as long as fPaused is set, menus opened from the
+// pause menu included.
+var (
+	fPaused     bool
+	idPauseItem int
+	startPause  time.Time
+)
+
+// StartGame starts a new game with a new seed.
+func StartGame() {
+	//--------------------------------------------------
+	game.curMode = PLAY
+	processEvents = ProcessEventsPlay
+	drawCurMode = DrawPlayMode
+	playInputs = playInputs[:0]
+	game.Seed = NewSeed()
+	game.DAS, game.ARR = settings.DAS, settings.ARR
+	game.RandomizerType = settings.Randomizer
+	game.Start()
+	game.Recorder = engine.ReplayNew(game.Game)
+}
+
+// releaseInputs lets go of the held moves, which the player may release
+// while the game does not listen.
+func releaseInputs(inputs []engine.InputEvent) []engine.InputEvent {
+	//--------------------------------------------------
+	for _, pa := range playActions {
+		if pa.fRelease {
+			inputs = append(inputs, engine.InputEvent{Action: pa.action, Pressed: false})
+		}
+	}
+	return inputs
+}
+
+// StartPause pauses the game on its next tick and opens the pause menu.
+// The held moves are released.
+func StartPause() {
+	//--------------------------------------------------
+	playInputs = releaseInputs(playInputs)
+	if !game.FPause {
+		playInputs = append(playInputs, engine.InputEvent{Action: engine.PAUSE, Pressed: true})
+	}
+	ShowPauseMenu()
+}
+
+// ShowPauseMenu opens the pause menu of a game already paused.
+func ShowPauseMenu() {
+	//--------------------------------------------------
+	fPaused = true
+	idPauseItem = PAUSE_RESUME
+	startPause = time.Now()
+	game.curMode = GAMEPAUSE
+	processEvents = ProcessEventsPause
+	drawCurMode = DrawPauseMode
+}
+
+func ResumePause() {
+	//--------------------------------------------------
+	fPaused = false
+	playInputs = append(playInputs, engine.InputEvent{Action: engine.PAUSE, Pressed: true})
+	DelayClearLabels(time.Since(startPause))
+	game.curMode = PLAY
+	processEvents = ProcessEventsPlay
+	drawCurMode = DrawPlayMode
+}
+
+func ProcessEventsPause(win pixelgl.Window) bool {
+
+	if controls.JustPressed(&win, CTL_PAUSE) || controls.JustPressed(&win, CTL_BACK) {
+		ResumePause()
+	} else if controls.JustPressed(&win, CTL_MENU_UP) {
+		idPauseItem = (idPauseItem + NB_PAUSE_ITEMS - 1) % NB_PAUSE_ITEMS
+	} else if controls.JustPressed(&win, CTL_MENU_DOWN) {
+		idPauseItem = (idPauseItem + 1) % NB_PAUSE_ITEMS
+	} else if controls.JustPressed(&win, CTL_VALIDATE) || controls.JustPressed(&win, CTL_START) {
+		switch idPauseItem {
+		case PAUSE_RESUME:
+			ResumePause()
+		case PAUSE_RESTART:
+			fPaused = false
+			SaveRecordedReplay()
+			StartGame()
+		case PAUSE_OPTIONS:
+			StartOptionsMode()
+		case PAUSE_QUIT:
+			//-- Kept to be continued, as with Escape while playing
+			fPaused = false
+			SuspendGame()
+			game.curMode = STANDBY
+			processEvents = ProcessEventsStandBy
+			drawCurMode = DrawStandByMode
+			game.Reset()
+		}
+	} else {
+		ProcessEventsSound(win)
+	}
+	return true
+}
+
+func DrawPauseMode(win pixel.Target) {
+
+	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
+	oy := float64(WIN_HEIGHT - TOP - 6*cellSize)
+	txt := text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Gold
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "PAUSE")
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	oy -= float64(cellSize + 4)
+	for i, item := range pauseItems {
+		oy -= float64(cellSize + 4)
+		txt = text.New(pixel.V(ox, oy), atlas)
+		txt.Color = colornames.Gold
+		if i == idPauseItem {
+			txt.Color = colornames.Orange
+			item = "> " + item + " <"
+		}
+		rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+		fmt.Fprintf(txt, "%s", item)
+		txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+	}
+
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"pixel_tetris/engine"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/pixelgl"
+	"github.com/gopxl/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+const REPLAYS_DIR = "replays"
+
+var (
+	replayPlayer   *engine.ReplayPlayer
+	lastReplayFile string
+	replayElapsed  time.Duration
+	fReplayPause   bool
+	fReplayFast    bool
+	fReplayStep    bool
+	backupGame     engine.Game
+)
+
+func SaveRecordedReplay() {
+	//--------------------------------------------------
+	recReplay := game.Recorder
+	game.Recorder = nil
+	if recReplay == nil || recReplay.NbTicks == 0 {
+		return
+	}
+	if err := os.MkdirAll(REPLAYS_DIR, 0755); err != nil {
+		log.Println(err)
+		return
+	}
+	fileName := filepath.Join(REPLAYS_DIR, time.Now().Format("20060102-150405")+".rpl")
+	if err := recReplay.Save(fileName); err != nil {
+		log.Println(err)
+		return
+	}
+	lastReplayFile = fileName
+}
+
+func StartReplay(fileName string) bool {
+	//--------------------------------------------------
+	re, err := engine.LoadReplay(fileName)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	//-- Keep the player's settings and last score aside
+	backupGame = *game.Game
+	replayPlayer = engine.ReplayPlayerNew(re, game.Game)
+	replayElapsed = 0
+	fReplayPause = false
+	fReplayFast = false
+	fReplayStep = false
+
+	game.curMode = REPLAY
+	processEvents = ProcessEventsReplay
+	drawCurMode = DrawReplayMode
+	return true
+}
+
+func StopReplay() {
+	//--------------------------------------------------
+	replayPlayer = nil
+	game.Config = backupGame.Config
+	game.Seed = backupGame.Seed
+	game.CurScore = backupGame.CurScore
+	game.Level = backupGame.Level
+	game.Lines = backupGame.Lines
+	game.Reset()
+
+	game.curMode = STANDBY
+	processEvents = ProcessEventsStandBy
+	drawCurMode = DrawStandByMode
+}
+
+func ProcessEventsReplay(win pixelgl.Window) bool {
+
+	if controls.JustPressed(&win, CTL_PAUSE) {
+		fReplayPause = !fReplayPause
+	} else if controls.JustPressed(&win, CTL_REPLAY_STEP) {
+		fReplayStep = true
+	} else if controls.JustPressed(&win, CTL_BACK) {
+		StopReplay()
+		return true
+	} else {
+		ProcessEventsSound(win)
+	}
+	fReplayFast = controls.Pressed(&win, CTL_REPLAY_FAST)
+
+	return true
+}
+
+func UpdateReplay(dt time.Duration) {
+	//--------------------------------------------------
+	if fReplayPause {
+		//-- Frame stepping
+		if fReplayStep {
+			fReplayStep = false
+			PlayReplayEvents(replayPlayer.Step())
+		}
+	} else {
+		if fReplayFast {
+			dt *= 4
+		}
+		replayElapsed += dt
+		for !replayPlayer.Done() && replayElapsed >= engine.TICK {
+			replayElapsed -= engine.TICK
+			PlayReplayEvents(replayPlayer.Step())
+		}
+	}
+
+	if replayPlayer.Done() {
+		StopReplay()
+	}
+}
+
+func PlayReplayEvents(events []engine.Event) {
+	//--------------------------------------------------
+	for _, ev := range events {
+		switch ev {
+		case engine.EV_LINE_ERASED:
+			PlaySuccesSound()
+		case engine.EV_CLEAR:
+			ShowClearLabels(game.LastClear)
+		}
+	}
+}
+
+func DrawReplayMode(win pixel.Target) {
+
+	DrawPlayMode(win)
+
+	status := "REPLAY"
+	if fReplayPause {
+		status = "REPLAY - PAUSE"
+	} else if fReplayFast {
+		status = "REPLAY x4"
+	}
+	oy := float64(WIN_HEIGHT - TOP - cellSize)
+	txt := text.New(pixel.V(0, 0), atlas)
+	txt.Color = colornames.Orange
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "%s", status)
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+}
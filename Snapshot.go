@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"pixel_tetris/engine"
+)
+
+// Game in progress saved on Escape or when the window is closed, offered
+// back on the standby screen.
+const SNAPSHOT_FILE = "Game.snp"
+
+var fSnapshot bool
+
+// SuspendGame saves the game in progress to be continued later, its held
+// moves released. Games of the bot are not kept.
+func SuspendGame() {
+	//--------------------------------------------------
+	game.Input(releaseInputs(nil))
+	sn := game.Snapshot()
+	game.Recorder = nil
+	if bot != nil {
+		return
+	}
+	if err := sn.Save(SNAPSHOT_FILE); err != nil {
+		log.Println(err)
+		return
+	}
+	fSnapshot = true
+}
+
+// ResumeGame continues the saved game, which is then forgotten so that it
+// can be continued only once.
+func ResumeGame() {
+	//--------------------------------------------------
+	sn, err := engine.LoadSnapshot(SNAPSHOT_FILE)
+	fSnapshot = false
+	os.Remove(SNAPSHOT_FILE)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	game.Restore(sn)
+	playInputs = playInputs[:0]
+	if game.FPause {
+		ShowPauseMenu()
+		return
+	}
+	game.curMode = PLAY
+	processEvents = ProcessEventsPlay
+	drawCurMode = DrawPlayMode
+}
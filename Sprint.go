@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+
+	"pixel_tetris/engine"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/pixelgl"
+	"github.com/gopxl/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+func ProcessEventsResults(win pixelgl.Window) bool {
+
+	if controls.JustPressed(&win, CTL_START) {
+		if game.idHighScore >= 0 {
+			//-- Let the player name the new record
+			game.curMode = HIGHSCORES
+			processEvents = ProcessEventsHightScores
+			drawCurMode = DrawHighScoresMode
+		} else {
+			game.curMode = STANDBY
+			processEvents = ProcessEventsStandBy
+			drawCurMode = DrawStandByMode
+		}
+	} else {
+		ProcessEventsSound(win)
+	}
+	return true
+}
+
+func DrawResultsMode(win pixel.Target) {
+
+	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
+	oy := float64(WIN_HEIGHT - TOP - 4*cellSize)
+	txt := text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Gold
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "%s COMPLETE", game.ModeName())
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	if game.Type == engine.ULTRA {
+		oy = DrawUltraBreakdown(win, oy)
+	} else {
+		oy = DrawSprintSplits(win, oy)
+	}
+
+	oy -= float64(2*cellSize + 4)
+	txt = text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Gold
+	rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "Press %s to Continue", controls.KeyName(CTL_START))
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	DrawSeed(win, oy-float64(cellSize+4), game.Seed)
+
+}
+
+func DrawSprintSplits(win pixel.Target, oy float64) float64 {
+
+	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
+	oy -= float64(2*cellSize + 4)
+	txt := text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Orange
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "TIME : %s", FormatDuration(game.PlayTime()))
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	oy -= float64(cellSize)
+	for i, split := range game.Splits {
+		oy -= float64(cellSize + 4)
+		txt = text.New(pixel.V(ox, oy), atlas)
+		txt.Color = colornames.Gold
+		rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+		fmt.Fprintf(txt, "%02d LINES : %s", (i+1)*engine.SPLIT_LINES, FormatDuration(split))
+		txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+	}
+	return oy
+}
+
+// DrawSprintPanel shows the running time and the split times under the
+// NEXT tetromino.
+func DrawSprintPanel(win pixel.Target) {
+
+	x := float64(LEFT + (NB_COLUMNS+1)*cellSize)
+	y := float64(WIN_HEIGHT-TOP-NB_ROWS*cellSize) + 7*float64(cellSize)
+	txt := text.New(pixel.V(x, y), atlas)
+	txt.Color = colornames.Gold
+	fmt.Fprintf(txt, "TIME")
+	txt.Draw(win, pixel.IM)
+
+	y -= float64(cellSize)
+	txt = text.New(pixel.V(x, y), atlas)
+	txt.Color = colornames.Orange
+	fmt.Fprintf(txt, "%s", FormatDuration(game.PlayTime()))
+	txt.Draw(win, pixel.IM)
+
+	for _, split := range game.Splits {
+		y -= float64(cellSize)
+		txt = text.New(pixel.V(x, y), atlas)
+		txt.Color = colornames.Gold
+		fmt.Fprintf(txt, "%s", FormatDuration(split))
+		txt.Draw(win, pixel.IM)
+	}
+
+}
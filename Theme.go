@@ -0,0 +1,59 @@
+package main
+
+import (
+	"image/color"
+
+	"golang.org/x/image/colornames"
+)
+
+// Theme gives the colors of the window, of the board and of each tetromino
+// type, index 0 being the empty cell.
+type Theme struct {
+	name       string
+	background color.RGBA
+	board      Color
+	pieces     []Color
+}
+
+var themes = []Theme{
+	{"CLASSIC", colornames.Darkblue, Color{R: 10, G: 10, B: 100, A: 0xFF}, []Color{
+		{R: 0, G: 0, B: 0, A: 0xFF},
+		{R: 0xFF, G: 0x60, B: 0x60, A: 0xFF},
+		{R: 0x60, G: 0xFF, B: 0x60, A: 0xFF},
+		{R: 0x60, G: 0x60, B: 0xFF, A: 0xFF},
+		{R: 0xCC, G: 0xCC, B: 0x60, A: 0xFF},
+		{R: 0xCC, G: 0x60, B: 0xCC, A: 0xFF},
+		{R: 0x60, G: 0xCC, B: 0xCC, A: 0xFF},
+		{R: 0xDA, G: 0xAA, B: 0x00, A: 0xFF}}},
+	{"DARK", color.RGBA{R: 16, G: 16, B: 16, A: 0xFF}, Color{R: 40, G: 40, B: 40, A: 0xFF}, []Color{
+		{R: 0, G: 0, B: 0, A: 0xFF},
+		{R: 0xE0, G: 0x40, B: 0x40, A: 0xFF},
+		{R: 0x40, G: 0xE0, B: 0x40, A: 0xFF},
+		{R: 0x40, G: 0xE0, B: 0xE0, A: 0xFF},
+		{R: 0xA0, G: 0x40, B: 0xE0, A: 0xFF},
+		{R: 0xE0, G: 0xE0, B: 0x40, A: 0xFF},
+		{R: 0x40, G: 0x60, B: 0xE0, A: 0xFF},
+		{R: 0xE0, G: 0x90, B: 0x30, A: 0xFF}}},
+	{"MONO", color.RGBA{A: 0xFF}, Color{R: 24, G: 24, B: 24, A: 0xFF}, []Color{
+		{R: 0, G: 0, B: 0, A: 0xFF},
+		{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF},
+		{R: 0xD8, G: 0xD8, B: 0xD8, A: 0xFF},
+		{R: 0xC0, G: 0xC0, B: 0xC0, A: 0xFF},
+		{R: 0xA8, G: 0xA8, B: 0xA8, A: 0xFF},
+		{R: 0x90, G: 0x90, B: 0x90, A: 0xFF},
+		{R: 0x78, G: 0x78, B: 0x78, A: 0xFF},
+		{R: 0x60, G: 0x60, B: 0x60, A: 0xFF}}},
+}
+
+var (
+	backgroundColor color.RGBA
+	boardColor      Color
+)
+
+func SetTheme(id int) {
+	//--------------------------------------------------
+	th := themes[id]
+	backgroundColor = th.background
+	boardColor = th.board
+	colors = th.pieces
+}
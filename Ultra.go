@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"pixel_tetris/engine"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+// DrawUltraPanel shows the countdown under the NEXT tetromino. It stops
+// along with the game when it is paused.
+func DrawUltraPanel(win pixel.Target) {
+
+	x := float64(LEFT + (NB_COLUMNS+1)*cellSize)
+	y := float64(WIN_HEIGHT-TOP-NB_ROWS*cellSize) + 7*float64(cellSize)
+	txt := text.New(pixel.V(x, y), atlas)
+	txt.Color = colornames.Gold
+	fmt.Fprintf(txt, "TIME LEFT")
+	txt.Draw(win, pixel.IM)
+
+	y -= float64(cellSize)
+	txt = text.New(pixel.V(x, y), atlas)
+	txt.Color = colornames.Orange
+	if game.FPause {
+		txt.Color = colornames.Gray
+	}
+	fmt.Fprintf(txt, "%s", FormatDuration(game.TimeLeft()))
+	txt.Draw(win, pixel.IM)
+
+}
+
+// DrawUltraBreakdown lists the points earned by each type of clear.
+func DrawUltraBreakdown(win pixel.Target, oy float64) float64 {
+
+	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
+	oy -= float64(2*cellSize + 4)
+	txt := text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Orange
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "SCORE : %06d", game.CurScore)
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	oy -= float64(cellSize)
+	for ct, cs := range game.Clears {
+		if cs.Count == 0 {
+			continue
+		}
+		oy -= float64(cellSize + 4)
+		txt = text.New(pixel.V(ox, oy), atlas)
+		txt.Color = colornames.Gold
+		rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+		fmt.Fprintf(txt, "%s x%d : %d", engine.ClearType(ct), cs.Count, cs.Points)
+		txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+	}
+	return oy
+}
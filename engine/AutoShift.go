@@ -0,0 +1,84 @@
+package engine
+
+import "time"
+
+// Horizontal movement follows the Delayed Auto Shift / Auto Repeat Rate
+// model: a press shifts the piece one cell at once, then, while the key is
+// held, the piece shifts again after DAS and every ARR after that. An ARR
+// of 0 moves the piece to the wall as soon as DAS is charged.
+
+func (ga *Game) pressShift(dir int32) {
+	//--------------------------------------------------
+	if dir < 0 {
+		ga.fLeftHeld = true
+	} else {
+		ga.fRightHeld = true
+	}
+	ga.startAutoShift(dir)
+	ga.ShiftTetromino(dir)
+}
+
+func (ga *Game) releaseShift(dir int32) {
+	//--------------------------------------------------
+	if dir < 0 {
+		ga.fLeftHeld = false
+	} else {
+		ga.fRightHeld = false
+	}
+	if ga.dasDir != dir {
+		return
+	}
+	//-- Fall back to the other direction if it is still held
+	if ga.fLeftHeld {
+		ga.startAutoShift(-1)
+	} else if ga.fRightHeld {
+		ga.startAutoShift(1)
+	} else {
+		ga.dasDir = 0
+	}
+}
+
+func (ga *Game) startAutoShift(dir int32) {
+	ga.dasDir = dir
+	ga.dasElapsed = 0
+	ga.nbAutoShifts = 0
+}
+
+func (ga *Game) updateAutoShift(dt time.Duration) {
+	//--------------------------------------------------
+	if ga.dasDir == 0 {
+		return
+	}
+	ga.dasElapsed += dt
+	if ga.dasElapsed < ga.DAS {
+		return
+	}
+	if ga.ARR <= 0 {
+		for ga.ShiftTetromino(ga.dasDir) {
+		}
+		return
+	}
+	nbDue := int((ga.dasElapsed-ga.DAS)/ga.ARR) + 1
+	for ; ga.nbAutoShifts < nbDue; ga.nbAutoShifts++ {
+		ga.ShiftTetromino(ga.dasDir)
+	}
+}
+
+// ShiftTetromino moves the falling tetromino one cell left (dir < 0) or
+// right (dir > 0) if nothing is in the way.
+func (ga *Game) ShiftTetromino(dir int32) bool {
+	//--------------------------------------------------
+	te := ga.CurTetromino
+	if te == nil {
+		return false
+	}
+	backupX := te.X
+	te.X += dir * CELL_SIZE
+	if te.IsOutBoardLimit() || te.HitGround(ga.Board) {
+		te.X = backupX
+		return false
+	}
+	ga.fLastRotation = false
+	ga.resetLockDelay()
+	return true
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// A held move shifts the tetromino once on the press, again once DAS is
+// charged, then every ARR up to the wall.
+func TestAutoShift(t *testing.T) {
+	const nbTicks, maxShifts = 80, NB_COLUMNS - 3
+	tests := []struct {
+		das, arr time.Duration
+	}{
+		{170 * time.Millisecond, 50 * time.Millisecond},
+		{100 * time.Millisecond, 10 * time.Millisecond},
+		{100 * time.Millisecond, 0},
+		{0, 0},
+		{0, 30 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		ga := GameNew(1)
+		ga.DAS, ga.ARR = tt.das, tt.arr
+		ga.Start()
+		ga.CurTetromino = pieceAt(3, 2, 5, 0)
+		x0 := ga.CurTetromino.X
+
+		inputs := []InputEvent{{Action: MOVE_RIGHT, Pressed: true}}
+		for tick := 1; tick <= nbTicks; tick++ {
+			ga.Step(inputs)
+			inputs = nil
+
+			want := 1
+			if held := time.Duration(tick) * TICK; held >= tt.das {
+				if tt.arr == 0 {
+					want = maxShifts
+				} else {
+					want += int((held-tt.das)/tt.arr) + 1
+				}
+			}
+			want = min(want, maxShifts)
+			if got := int((ga.CurTetromino.X - x0) / CELL_SIZE); got != want {
+				t.Errorf("DAS %v, ARR %v, held %d ticks: shifted %d cells, want %d",
+					tt.das, tt.arr, tick, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestAutoShiftRelease(t *testing.T) {
+	ga := GameNew(1)
+	ga.DAS, ga.ARR = 100*time.Millisecond, 0
+	ga.Start()
+	ga.CurTetromino = pieceAt(3, 5, 5, 0)
+	x0 := ga.CurTetromino.X
+
+	//-- Released before DAS is charged: a single shift
+	ga.Step([]InputEvent{{Action: MOVE_LEFT, Pressed: true}})
+	ga.Step([]InputEvent{{Action: MOVE_LEFT, Pressed: false}})
+	for i := 0; i < 30; i++ {
+		ga.Step(nil)
+	}
+	if got := (ga.CurTetromino.X - x0) / CELL_SIZE; got != -1 {
+		t.Errorf("tapped left: shifted %d cells, want -1", got)
+	}
+
+	//-- Both held: the last pressed wins, the other one takes over on its
+	//-- release with its DAS to charge again
+	ga.Step([]InputEvent{{Action: MOVE_LEFT, Pressed: true}})
+	ga.Step([]InputEvent{{Action: MOVE_RIGHT, Pressed: true}})
+	if got := (ga.CurTetromino.X - x0) / CELL_SIZE; got != -1 {
+		t.Errorf("left then right: shifted %d cells, want -1", got)
+	}
+	ga.Step([]InputEvent{{Action: MOVE_RIGHT, Pressed: false}})
+	if got := (ga.CurTetromino.X - x0) / CELL_SIZE; got != -1 {
+		t.Errorf("right released: shifted %d cells, want -1", got)
+	}
+	for i := 0; i < int(ga.DAS/TICK); i++ {
+		ga.Step(nil)
+	}
+	if got := ga.CurTetromino.X / CELL_SIZE; got != 0 {
+		t.Errorf("left held again: column %d, want 0", got)
+	}
+}
@@ -0,0 +1,341 @@
+package engine
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// The bot searches every placement the falling tetromino can reach from
+// where it spawned, breadth first over shifts, turns and a soft drop down
+// to the ground, followed by more shifts and turns there to tuck or spin
+// it. It rates the board each placement leaves and plays the best one as
+// ordinary inputs, ending with a hard drop.
+
+// BotWeights rate a board: each one multiplies a feature of the board left
+// by a placement.
+type BotWeights struct {
+	Lines     float64
+	Height    float64
+	Holes     float64
+	Bumpiness float64
+}
+
+// Weights tuned by Yiyuan Lee with a genetic algorithm.
+var BOT_WEIGHTS = BotWeights{
+	Lines:     0.760666,
+	Height:    -0.510066,
+	Holes:     -0.35663,
+	Bumpiness: -0.184483,
+}
+
+// Placement is a way to lock a tetromino: the Moves that bring it there
+// before a hard drop. FAST_DOWN stands for a soft drop down to the ground;
+// the moves after it are made on the ground.
+type Placement struct {
+	Moves     []Action
+	Tetromino Tetromino
+	Score     float64
+}
+
+var botMoves = []Action{MOVE_LEFT, MOVE_RIGHT, ROTATE_RIGHT, ROTATE_LEFT, FAST_DOWN}
+
+// botState is a position met by the search, with the move from its parent.
+type botState struct {
+	te       Tetromino
+	fDropped bool
+	parent   int
+	move     Action
+}
+
+func (st *botState) key() [4]int32 {
+	dropped := int32(0)
+	if st.fDropped {
+		dropped = 1
+	}
+	return [4]int32{st.te.X, st.te.Y, st.te.Rot, dropped}
+}
+
+// Placements lists the distinct positions te can lock at from where it is,
+// rated with w. Every position in the air is searched before those on the
+// ground, so that a placement is reached by dropping as late as it can.
+func (ga *Game) Placements(te *Tetromino, w BotWeights) []Placement {
+	//--------------------------------------------------
+	var places []Placement
+	seen := map[[4]int32]bool{}
+	seenCells := map[[4]int]bool{}
+	states := []botState{{te: *te, parent: -1}}
+	seen[states[0].key()] = true
+	var dropped []botState
+	for i := 0; ; i++ {
+		if i == len(states) {
+			if len(dropped) == 0 {
+				break
+			}
+			states = append(states, dropped...)
+			dropped = nil
+		}
+		st := states[i]
+		if st.fDropped {
+			if cells := ga.cellsOf(&st.te); !seenCells[cells] {
+				seenCells[cells] = true
+				places = append(places, Placement{
+					Moves:     botPath(states, i),
+					Tetromino: st.te,
+					Score:     ga.rate(cells, w),
+				})
+			}
+			if ga.SoftDropLock {
+				//-- Locked as soon as it lands
+				continue
+			}
+		}
+		for _, move := range botMoves {
+			next := botState{te: st.te, fDropped: st.fDropped, parent: i, move: move}
+			if !ga.botMove(&next, move) || seen[next.key()] {
+				continue
+			}
+			seen[next.key()] = true
+			if next.fDropped && !st.fDropped {
+				dropped = append(dropped, next)
+			} else {
+				states = append(states, next)
+			}
+		}
+	}
+	return places
+}
+
+// botMove makes a move of the search, false if it is blocked. Once dropped,
+// the tetromino falls back to the ground after each move.
+func (ga *Game) botMove(st *botState, move Action) bool {
+	//--------------------------------------------------
+	te := &st.te
+	switch move {
+	case MOVE_LEFT, MOVE_RIGHT:
+		dir := int32(-1)
+		if move == MOVE_RIGHT {
+			dir = 1
+		}
+		te.X += dir * CELL_SIZE
+		if te.IsOutBoardLimit() || te.HitGround(ga.Board) {
+			return false
+		}
+	case ROTATE_RIGHT:
+		if _, ok := ga.turnTetromino(te, (*Tetromino).RotateRight); !ok {
+			return false
+		}
+	case ROTATE_LEFT:
+		if _, ok := ga.turnTetromino(te, (*Tetromino).RotateLeft); !ok {
+			return false
+		}
+	case FAST_DOWN:
+		if st.fDropped {
+			return false
+		}
+		st.fDropped = true
+	}
+	if st.fDropped {
+		ga.land(te)
+	}
+	return true
+}
+
+// botPath returns the moves from the first state to states[i].
+func botPath(states []botState, i int) []Action {
+	//--------------------------------------------------
+	var moves []Action
+	for ; states[i].parent >= 0; i = states[i].parent {
+		moves = append(moves, states[i].move)
+	}
+	slices.Reverse(moves)
+	return moves
+}
+
+// cellsOf returns the board indexes of the cells of te in increasing order,
+// negative above the board.
+func (ga *Game) cellsOf(te *Tetromino) [4]int {
+	//--------------------------------------------------
+	var cells [4]int
+	offSet := int32(NB_ROWS * CELL_SIZE)
+	ix := (te.X + 1) / CELL_SIZE
+	iy := (offSet - te.Y + 1) / CELL_SIZE
+	for i, v := range te.V {
+		x := v.X + ix
+		y := iy - v.Y
+		cells[i] = int(y*NB_COLUMNS + x)
+	}
+	slices.Sort(cells[:])
+	return cells
+}
+
+// rate scores the board once cells are filled and completed lines erased.
+func (ga *Game) rate(cells [4]int, w BotWeights) float64 {
+	//--------------------------------------------------
+	board := make([]int, 0, NB_ROWS*NB_COLUMNS)
+	filled := append([]int(nil), ga.Board...)
+	for _, c := range cells {
+		if c < NB_COLUMNS {
+			//-- Locking in the top row or above it tops out
+			return math.Inf(-1)
+		}
+		filled[c] = 1
+	}
+
+	//-- Keep the rows that are not completed, pushed down
+	nbLines := 0
+	for r := 0; r < NB_ROWS; r++ {
+		row := filled[r*NB_COLUMNS : (r+1)*NB_COLUMNS]
+		fCompleted := true
+		for _, v := range row {
+			if v == 0 {
+				fCompleted = false
+				break
+			}
+		}
+		if fCompleted {
+			nbLines++
+		} else {
+			board = append(board, row...)
+		}
+	}
+	board = append(make([]int, nbLines*NB_COLUMNS), board...)
+
+	var heights [NB_COLUMNS]int
+	nbHoles := 0
+	for c := 0; c < NB_COLUMNS; c++ {
+		for r := 0; r < NB_ROWS; r++ {
+			if board[r*NB_COLUMNS+c] != 0 {
+				if heights[c] == 0 {
+					heights[c] = NB_ROWS - r
+				}
+			} else if heights[c] != 0 {
+				nbHoles++
+			}
+		}
+	}
+	height, bumpiness := 0, 0
+	for c, h := range heights {
+		height += h
+		if c > 0 {
+			bumpiness += max(h-heights[c-1], heights[c-1]-h)
+		}
+	}
+
+	return w.Lines*float64(nbLines) + w.Height*float64(height) +
+		w.Holes*float64(nbHoles) + w.Bumpiness*float64(bumpiness)
+}
+
+// BestPlacement returns the best rated placement of te, or false when it
+// cannot move at all.
+func (ga *Game) BestPlacement(te *Tetromino, w BotWeights) (Placement, bool) {
+	//--------------------------------------------------
+	var best Placement
+	ok := false
+	for _, pl := range ga.Placements(te, w) {
+		if !ok || pl.Score > best.Score {
+			best = pl
+			ok = true
+		}
+	}
+	return best, ok
+}
+
+// Bot plays a game by sending it the inputs a player would, one every
+// Delay, or all at once when Delay is 0. Once it has soft dropped a
+// tetromino, it waits for it to be on the ground before each move.
+type Bot struct {
+	Weights BotWeights
+	Delay   time.Duration
+	planned *Tetromino
+	plan    []botInput
+	elapsed time.Duration
+}
+
+// botInput is an input of a plan, to be sent only on the ground if
+// fGrounded is set.
+type botInput struct {
+	InputEvent
+	fGrounded bool
+}
+
+func BotNew() *Bot {
+	return &Bot{Weights: BOT_WEIGHTS}
+}
+
+// Inputs returns the inputs for the next dt of game, to be given to
+// Update or Step along with dt.
+func (bo *Bot) Inputs(ga *Game, dt time.Duration) []InputEvent {
+	//--------------------------------------------------
+	if ga.Mode != PLAY || ga.FPause || ga.CurTetromino == nil || ga.nbCompledLines > 0 {
+		return nil
+	}
+	if ga.CurTetromino != bo.planned {
+		bo.planned = ga.CurTetromino
+		bo.plan = ga.botPlan(bo.Weights)
+		bo.elapsed = 0
+	}
+	bo.elapsed += dt
+	var inputs []InputEvent
+	fGroundedSent := false
+	for len(bo.plan) > 0 && (bo.Delay <= 0 || bo.elapsed >= bo.Delay) {
+		in := bo.plan[0]
+		if in.fGrounded && (!ga.fGrounded || fGroundedSent) {
+			//-- The last move may lift the tetromino: wait for the next tick
+			bo.elapsed = min(bo.elapsed, bo.Delay)
+			break
+		}
+		fGroundedSent = fGroundedSent || in.fGrounded
+		bo.elapsed -= bo.Delay
+		inputs = append(inputs, in.InputEvent)
+		bo.plan = bo.plan[1:]
+	}
+	if bo.Delay <= 0 {
+		bo.elapsed = 0
+	}
+	return inputs
+}
+
+// botPlan returns the inputs that lock the falling tetromino at its best
+// placement, or that hold it when the other piece does better.
+func (ga *Game) botPlan(w BotWeights) []botInput {
+	//--------------------------------------------------
+	best, ok := ga.BestPlacement(ga.CurTetromino, w)
+	if !ga.fHoldUsed {
+		other := ga.NextTetrominos[0]
+		if ga.HoldTetromino != nil {
+			other = ga.HoldTetromino
+		}
+		alt := TetrominoNew(other.Typ, 0, 0)
+		moveToSpawn(alt)
+		if altBest, altOk := ga.BestPlacement(alt, w); altOk && (!ok || altBest.Score > best.Score) {
+			return []botInput{{InputEvent: InputEvent{Action: HOLD, Pressed: true}}}
+		}
+	}
+	hardDrop := botInput{InputEvent: InputEvent{Action: HARD_DROP, Pressed: true}}
+	if !ok {
+		return []botInput{hardDrop}
+	}
+
+	//-- A drop as the last move is the hard drop itself
+	moves := best.Moves
+	if len(moves) > 0 && moves[len(moves)-1] == FAST_DOWN {
+		moves = moves[:len(moves)-1]
+	}
+	var plan []botInput
+	fDropped := false
+	for _, move := range moves {
+		plan = append(plan, botInput{InputEvent{Action: move, Pressed: true}, fDropped})
+		switch move {
+		case MOVE_LEFT, MOVE_RIGHT:
+			plan = append(plan, botInput{InputEvent: InputEvent{Action: move, Pressed: false}})
+		case FAST_DOWN:
+			fDropped = true
+		}
+	}
+	if fDropped {
+		plan = append(plan, botInput{InputEvent{Action: FAST_DOWN, Pressed: false}, true})
+		hardDrop.fGrounded = true
+	}
+	return append(plan, hardDrop)
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPlacementsEmptyBoard(t *testing.T) {
+	ga := GameNew(1)
+	for _, tc := range []struct {
+		typ int32
+		nb  int
+	}{
+		//-- 2 orientations: lying 9 columns wide, standing 12
+		{3, 9 + 12},
+		{5, NB_COLUMNS - 1},
+		//-- 2 orientations 10 columns wide, 2 standing 11
+		{4, 2*10 + 2*11},
+	} {
+		te := TetrominoNew(tc.typ, 0, 0)
+		moveToSpawn(te)
+		if nb := len(ga.Placements(te, BOT_WEIGHTS)); nb != tc.nb {
+			t.Errorf("type %d: %d placements, want %d", tc.typ, nb, tc.nb)
+		}
+	}
+}
+
+// An I lying under an overhang is out of reach of a straight drop: it is
+// dropped beside it, then shifted under it on the ground.
+func TestBotTuck(t *testing.T) {
+	rows := []string{
+		"XXXX........",
+		"............",
+		"............"}
+	tucked := func(ga *Game) bool {
+		for c := 0; c < 4; c++ {
+			if ga.Board[(NB_ROWS-1)*NB_COLUMNS+c] == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	ga := GameNew(1)
+	copy(ga.Board, boardOf(rows...))
+	te := TetrominoNew(3, 0, 0)
+	moveToSpawn(te)
+	best, ok := ga.BestPlacement(te, BOT_WEIGHTS)
+	if !ok {
+		t.Fatal("no placement")
+	}
+	if i := slices.Index(best.Moves, FAST_DOWN); i < 0 || i == len(best.Moves)-1 {
+		t.Fatalf("moves %v: no move on the ground", best.Moves)
+	}
+	ga.FreezeTetromino(&best.Tetromino)
+	if !tucked(ga) {
+		t.Fatalf("moves %v: not under the overhang", best.Moves)
+	}
+
+	//-- Played as inputs, at once or one every 40ms
+	for _, delay := range []time.Duration{0, 40 * time.Millisecond} {
+		ga := GameNew(1)
+		ga.Start()
+		copy(ga.Board, boardOf(rows...))
+		ga.CurTetromino = TetrominoNew(3, 0, 0)
+		moveToSpawn(ga.CurTetromino)
+		ga.fHoldUsed = true
+		bo := BotNew()
+		bo.Delay = delay
+		te := ga.CurTetromino
+		for ga.CurTetromino == te && ga.Tick < 2000 {
+			ga.Step(bo.Inputs(ga, TICK))
+		}
+		if !tucked(ga) {
+			t.Errorf("delay %v: not played under the overhang", delay)
+		}
+	}
+}
+
+func TestBotFillsWell(t *testing.T) {
+	ga := GameNew(1)
+	//-- Bottom 4 rows full but for the last column
+	for r := NB_ROWS - 4; r < NB_ROWS; r++ {
+		for c := 0; c < NB_COLUMNS-1; c++ {
+			ga.Board[r*NB_COLUMNS+c] = 1
+		}
+	}
+	te := TetrominoNew(3, 0, 0)
+	moveToSpawn(te)
+	best, ok := ga.BestPlacement(te, BOT_WEIGHTS)
+	if !ok {
+		t.Fatal("no placement")
+	}
+	ga.FreezeTetromino(&best.Tetromino)
+	if ga.nbCompledLines != 4 {
+		t.Errorf("I piece completes %d lines, want a tetris", ga.nbCompledLines)
+	}
+}
+
+// botGame lets a bot play a MARATHON until it tops out or locks nbPieces
+// tetrominos.
+func botGame(seed int64, nbPieces int) (*Game, int) {
+	ga := GameNew(seed)
+	ga.Start()
+	bo := BotNew()
+	nbLocked := 0
+	for ga.Mode == PLAY && nbLocked < nbPieces {
+		for _, ev := range ga.Step(bo.Inputs(ga, TICK)) {
+			if ev == EV_LOCKED {
+				nbLocked++
+			}
+		}
+	}
+	return ga, nbLocked
+}
+
+func TestBotPlays(t *testing.T) {
+	const nbPieces = 500
+	ga, nbLocked := botGame(1, nbPieces)
+	if nbLocked < nbPieces {
+		t.Fatalf("bot topped out after %d pieces", nbLocked)
+	}
+	//-- Each piece fills 4 cells: most of them must have been cleared
+	if ga.Lines < nbPieces*4/NB_COLUMNS-NB_ROWS {
+		t.Errorf("bot cleared only %d lines with %d pieces", ga.Lines, nbPieces)
+	}
+}
+
+func TestBotDeterministic(t *testing.T) {
+	a, _ := botGame(3, 200)
+	b, _ := botGame(3, 200)
+	if a.CurScore != b.CurScore || a.Lines != b.Lines {
+		t.Errorf("same seed, different games: %d/%d and %d/%d", a.CurScore, a.Lines, b.CurScore, b.Lines)
+	}
+}
@@ -0,0 +1,711 @@
+// Package engine holds the Tetris rules and simulation, independent of any
+// rendering or windowing library.
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	NB_ROWS    = 20
+	NB_COLUMNS = 12
+	CELL_SIZE  = 25
+)
+
+// The simulation always advances by whole ticks of TICK, whatever the
+// frame rate of the caller.
+const (
+	TICK_RATE = 120
+	TICK      = time.Second / TICK_RATE
+)
+
+// Longest frame Update catches up with, so that a stalled caller does not
+// make the game jump ahead.
+const maxFrameTime = 250 * time.Millisecond
+
+// Number of upcoming tetrominos known ahead, the most a preview can show.
+const PREVIEW_SIZE = 6
+
+// Soft drop speed, used unless gravity is already faster.
+const fastDownStep = 10 * time.Millisecond / 3
+
+// Sonic drop speed: SONIC_DROP_UNITS board units every sonicDropStep.
+const (
+	SONIC_DROP_UNITS = 6
+	sonicDropStep    = 10 * time.Millisecond
+)
+
+type GameMode int
+
+const (
+	STANDBY GameMode = iota
+	PLAY
+	GAMEOVER
+	FINISHED
+)
+
+// GameType is the goal of a game: MARATHON goes on until the stack tops
+// out, SPRINT ends once SPRINT_LINES lines are cleared and ULTRA when its
+// UltraTime is over.
+type GameType int
+
+const (
+	MARATHON GameType = iota
+	SPRINT
+	ULTRA
+	NB_GAME_TYPES
+)
+
+var gameTypeNames = [NB_GAME_TYPES]string{"MARATHON", "SPRINT", "ULTRA"}
+
+func (t GameType) String() string {
+	return gameTypeNames[t]
+}
+
+const (
+	SPRINT_LINES = 40
+	SPLIT_LINES  = 10
+)
+
+// Config holds the rules a game is played with. It is saved along with
+// replays and snapshots, so the rule functions are given by their type.
+// A LinesPerLevel of 0 keeps the game at its StartLevel.
+type Config struct {
+	Type           GameType
+	RandomizerType RandomizerType
+	KickTable      KickTableType
+	Gravity        GravityType
+	StartLevel     int
+	LinesPerLevel  int
+	LockDelay      time.Duration
+	MaxLockResets  int
+	SoftDropLock   bool
+	SonicDropLock  bool
+	DAS            time.Duration
+	ARR            time.Duration
+	UltraTime      time.Duration
+	StaticPreview  bool
+}
+
+func ConfigDefault() Config {
+	return Config{
+		Type:           MARATHON,
+		RandomizerType: BAG_14,
+		KickTable:      SRS_KICKS,
+		Gravity:        GUIDELINE_GRAVITY,
+		StartLevel:     1,
+		LinesPerLevel:  10,
+		LockDelay:      500 * time.Millisecond,
+		MaxLockResets:  15,
+		SonicDropLock:  true,
+		DAS:            170 * time.Millisecond,
+		ARR:            50 * time.Millisecond,
+		UltraTime:      2 * time.Minute,
+	}
+}
+
+// check tells why a loaded config cannot be played, if it cannot.
+func (cfg *Config) check() error {
+	//--------------------------------------------------
+	switch {
+	case cfg.Type < 0 || cfg.Type >= NB_GAME_TYPES:
+		return fmt.Errorf("unknown game type %d", cfg.Type)
+	case cfg.RandomizerType < 0 || cfg.RandomizerType >= NB_RANDOMIZERS:
+		return fmt.Errorf("unknown randomizer %d", cfg.RandomizerType)
+	case cfg.KickTable < 0 || cfg.KickTable >= NB_KICK_TABLES:
+		return fmt.Errorf("unknown kick table %d", cfg.KickTable)
+	case cfg.Gravity < 0 || cfg.Gravity >= NB_GRAVITIES:
+		return fmt.Errorf("unknown gravity %d", cfg.Gravity)
+	case cfg.StartLevel < 1 || cfg.LinesPerLevel < 0:
+		return fmt.Errorf("invalid levels, from %d every %d lines", cfg.StartLevel, cfg.LinesPerLevel)
+	}
+	return nil
+}
+
+type Game struct {
+	Config
+	Mode           GameMode
+	Seed           int64
+	Tick           int
+	Board          []int
+	CurTetromino   *Tetromino
+	NextTetrominos []*Tetromino
+	HoldTetromino  *Tetromino
+	CurScore       int
+	Level          int
+	Lines          int
+	Splits         []time.Duration
+	Clears         [NB_CLEAR_TYPES]ClearStat
+	LastClear      Clear
+	FPause         bool
+	Recorder       *Replay
+	fHoldUsed      bool
+	fSonicDrop     bool
+	fHardDrop      bool
+	fFastDown      bool
+	fLeftHeld      bool
+	fRightHeld     bool
+	dasDir         int32
+	dasElapsed     time.Duration
+	nbAutoShifts   int
+	nbCompledLines int
+	combo          int
+	fB2B           bool
+	fLastRotation  bool
+	lastKick       int
+	dropUnits      int32
+	fGrounded      bool
+	lockElapsed    time.Duration
+	nbLockResets   int
+	lowestY        int32
+	elapsedV       time.Duration
+	elapsedR       time.Duration
+	accumulator    time.Duration
+	pendingInputs  []InputEvent
+	recorded       []InputEvent
+	prevTetromino  *Tetromino
+	prevY          int32
+	randomizer     Randomizer
+	nbDealt        int
+	events         []Event
+}
+
+func GameNew(seed int64) *Game {
+	game := &Game{
+		Config: ConfigDefault(),
+		Mode:   STANDBY,
+		Seed:   seed,
+		Board:  make([]int, NB_ROWS*NB_COLUMNS),
+		Level:  1,
+	}
+	game.initRandomizer()
+	return game
+}
+
+// initRandomizer restarts the piece sequence from Seed, so that two games
+// started with the same seed get the same tetrominos.
+func (ga *Game) initRandomizer() {
+	//--------------------------------------------------
+	ga.randomizer = RandomizerNew(ga.RandomizerType, rand.New(rand.NewSource(ga.Seed)))
+	ga.nbDealt = 0
+	ga.NextTetrominos = ga.NextTetrominos[:0]
+	for len(ga.NextTetrominos) < PREVIEW_SIZE {
+		ga.NextTetrominos = append(ga.NextTetrominos, ga.deal())
+	}
+}
+
+// deal draws the next tetromino of the sequence.
+func (ga *Game) deal() *Tetromino {
+	ga.nbDealt++
+	return TetrominoNew(ga.randomizer.Next(), 0, 0)
+}
+
+func (ga *Game) Start() {
+	//--------------------------------------------------
+	ga.ClearBoard()
+	ga.initRandomizer()
+	ga.Mode = PLAY
+	ga.Tick = 0
+	ga.CurScore = 0
+	ga.Level = ga.StartLevel
+	ga.Lines = 0
+	ga.Splits = ga.Splits[:0]
+	ga.Clears = [NB_CLEAR_TYPES]ClearStat{}
+	ga.LastClear = Clear{}
+	ga.combo = -1
+	ga.fB2B = false
+	ga.resetState()
+	ga.NewTetromino()
+}
+
+func (ga *Game) Reset() {
+	//--------------------------------------------------
+	ga.ClearBoard()
+	ga.Mode = STANDBY
+	ga.CurTetromino = nil
+	ga.resetState()
+}
+
+func (ga *Game) resetState() {
+	ga.HoldTetromino = nil
+	ga.FPause = false
+	ga.fHoldUsed = false
+	ga.fSonicDrop = false
+	ga.fHardDrop = false
+	ga.fFastDown = false
+	ga.fLeftHeld = false
+	ga.fRightHeld = false
+	ga.dasDir = 0
+	ga.nbCompledLines = 0
+	ga.elapsedV = 0
+	ga.elapsedR = 0
+	ga.accumulator = 0
+	ga.pendingInputs = ga.pendingInputs[:0]
+	ga.prevTetromino = nil
+}
+
+func (ga *Game) NewTetromino() {
+	//--------------------------------------------------
+	ga.spawnTetromino(ga.NextTetrominos[0])
+	copy(ga.NextTetrominos, ga.NextTetrominos[1:])
+	ga.NextTetrominos[PREVIEW_SIZE-1] = ga.deal()
+
+}
+
+func (ga *Game) spawnTetromino(te *Tetromino) {
+	//--------------------------------------------------
+	ga.CurTetromino = te
+	moveToSpawn(te)
+	ga.fGrounded = false
+	ga.lockElapsed = 0
+	ga.nbLockResets = 0
+	ga.lowestY = ga.CurTetromino.Y
+	ga.fLastRotation = false
+	ga.lastKick = 0
+	ga.dropUnits = 0
+}
+
+// moveToSpawn puts te where tetrominos enter the board, above its top.
+func moveToSpawn(te *Tetromino) {
+	te.X = 6 * CELL_SIZE
+	te.Y = (NB_ROWS+2)*CELL_SIZE + te.MaxY()*CELL_SIZE
+}
+
+// HoldCurTetromino swaps the falling tetromino with the held one, or with
+// the next one when nothing is held yet. It is allowed once per piece.
+func (ga *Game) HoldCurTetromino() bool {
+	//--------------------------------------------------
+	if ga.CurTetromino == nil || ga.fHoldUsed {
+		return false
+	}
+	held := ga.HoldTetromino
+	ga.HoldTetromino = TetrominoNew(ga.CurTetromino.Typ, 0, 0)
+	if held == nil {
+		ga.NewTetromino()
+	} else {
+		ga.spawnTetromino(held)
+	}
+	ga.fHoldUsed = true
+	ga.fSonicDrop = false
+	ga.fHardDrop = false
+	return true
+}
+
+func (ga *Game) ProcessInput(in InputEvent) {
+	//--------------------------------------------------
+	if ga.CurTetromino == nil {
+		return
+	}
+	if !in.Pressed {
+		switch in.Action {
+		case MOVE_LEFT:
+			ga.releaseShift(-1)
+		case MOVE_RIGHT:
+			ga.releaseShift(1)
+		case FAST_DOWN:
+			ga.fFastDown = false
+		}
+		return
+	}
+	if ga.FPause && in.Action != PAUSE {
+		return
+	}
+	switch in.Action {
+	case PAUSE:
+		ga.FPause = !ga.FPause
+	case MOVE_LEFT:
+		ga.pressShift(-1)
+	case MOVE_RIGHT:
+		ga.pressShift(1)
+	case ROTATE_LEFT:
+		ga.RotateTetromino(false)
+	case ROTATE_RIGHT:
+		ga.RotateTetromino(true)
+	case ROTATE_180:
+		ga.RotateTetromino180()
+	case FAST_DOWN:
+		ga.fFastDown = true
+	case SONIC_DROP:
+		ga.fSonicDrop = true
+	case HARD_DROP:
+		ga.fHardDrop = true
+	case HOLD:
+		ga.HoldCurTetromino()
+	}
+}
+
+// Update advances the simulation by as many ticks as fit in dt plus the
+// time left over by the previous call. The inputs are applied on the next
+// tick to run. It returns the events raised during those ticks.
+func (ga *Game) Update(dt time.Duration, inputs []InputEvent) []Event {
+	//--------------------------------------------------
+	ga.events = ga.events[:0]
+	if ga.Mode != PLAY {
+		return ga.events
+	}
+
+	ga.pendingInputs = append(ga.pendingInputs, inputs...)
+	ga.accumulator += min(dt, maxFrameTime)
+	for ga.accumulator >= TICK && ga.Mode == PLAY {
+		ga.accumulator -= TICK
+		ga.step(ga.pendingInputs)
+		ga.pendingInputs = ga.pendingInputs[:0]
+	}
+	return ga.events
+}
+
+// Step advances the simulation by exactly one tick.
+func (ga *Game) Step(inputs []InputEvent) []Event {
+	//--------------------------------------------------
+	ga.events = ga.events[:0]
+	if ga.Mode == PLAY {
+		ga.step(inputs)
+	}
+	return ga.events
+}
+
+// Input applies inputs at once, between two ticks, as when the game is
+// left with moves held. They are recorded on the next tick played.
+func (ga *Game) Input(inputs []InputEvent) {
+	//--------------------------------------------------
+	//-- Pauses are left out of replays: only the inputs that take effect
+	//-- are recorded
+	ga.recorded = ga.recorded[:0]
+	for _, in := range inputs {
+		if in.Action != PAUSE && (!in.Pressed || !ga.FPause) {
+			ga.recorded = append(ga.recorded, in)
+		}
+		ga.ProcessInput(in)
+	}
+	if ga.Recorder != nil {
+		ga.Recorder.record(ga.Tick, ga.recorded)
+	}
+}
+
+// PlayTime is the simulated time since Start, pauses left out.
+func (ga *Game) PlayTime() time.Duration {
+	return time.Duration(ga.Tick) * TICK
+}
+
+// TimeLeft is what remains of the clock of an ULTRA game.
+func (ga *Game) TimeLeft() time.Duration {
+	return max(ga.UltraTime-ga.PlayTime(), 0)
+}
+
+// RenderY is the height to draw the falling tetromino at, interpolated
+// between the last two ticks with the time left in the accumulator.
+func (ga *Game) RenderY() int32 {
+	//--------------------------------------------------
+	te := ga.CurTetromino
+	if te == nil {
+		return 0
+	}
+	if te != ga.prevTetromino {
+		return te.Y
+	}
+	alpha := float64(ga.accumulator) / float64(TICK)
+	return ga.prevY + int32(math.Round(float64(te.Y-ga.prevY)*alpha))
+}
+
+func (ga *Game) step(inputs []InputEvent) {
+	//--------------------------------------------------
+	dt := TICK
+	ga.prevTetromino = ga.CurTetromino
+	if ga.CurTetromino != nil {
+		ga.prevY = ga.CurTetromino.Y
+	}
+
+	ga.Input(inputs)
+	if ga.FPause {
+		return
+	}
+	if ga.Recorder != nil {
+		ga.Recorder.NbTicks = ga.Tick + 1
+	}
+	ga.Tick++
+
+	ga.elapsedV += dt
+	ga.elapsedR += dt
+
+	if ga.nbCompledLines > 0 {
+		//-- Remove Completed lines
+		if ga.elapsedV > 250*time.Millisecond {
+			ga.elapsedV = 0
+			ga.nbCompledLines--
+			ga.EraseFirstCompletedLine()
+			ga.events = append(ga.events, EV_LINE_ERASED)
+		}
+	} else if ga.fHardDrop {
+		ga.hardDrop()
+	} else if ga.fSonicDrop {
+		//-- Sonic drop: slide down fast, still under control
+		ga.updateAutoShift(dt)
+		nbUnits := 0
+		for ; ga.elapsedV >= sonicDropStep; ga.elapsedV -= sonicDropStep {
+			nbUnits += SONIC_DROP_UNITS
+		}
+		if nbUnits > 0 {
+			ga.fallDown(nbUnits)
+		}
+	} else {
+		//-- Move down Tetromino
+		ga.updateAutoShift(dt)
+		stepV := gravityCurves[ga.Gravity](ga.Level) / CELL_SIZE
+		if ga.fFastDown && stepV > fastDownStep {
+			stepV = fastDownStep
+		}
+		if stepV <= 0 {
+			stepV = 1
+		}
+		if ga.elapsedV >= stepV {
+			nbSteps := int(ga.elapsedV / stepV)
+			ga.elapsedV -= time.Duration(nbSteps) * stepV
+			ga.fallDown(nbSteps)
+		}
+	}
+
+	if ga.nbCompledLines == 0 && ga.CurTetromino != nil {
+		ga.updateLock(dt)
+	}
+
+	if ga.IsGoalReached() {
+		ga.finish()
+		return
+	}
+
+	//-- Check Game Over
+	if ga.IsGameOver() {
+		if ga.Type != ULTRA {
+			ga.Mode = GAMEOVER
+			ga.CurTetromino = nil
+			ga.events = append(ga.events, EV_GAMEOVER)
+			return
+		}
+		//-- Only the clock ends an ULTRA game: go on with an empty board
+		ga.ClearBoard()
+		ga.nbCompledLines = 0
+	}
+
+	//-- The next tetromino spins in the preview, and spawns as it is shown
+	if ga.elapsedR > 500*time.Millisecond && !ga.StaticPreview {
+		ga.elapsedR = 0
+		ga.NextTetrominos[0].RotateRight()
+	}
+}
+
+// IsGoalReached tells whether the game is over without being lost, which
+// never happens in MARATHON.
+func (ga *Game) IsGoalReached() bool {
+	switch ga.Type {
+	case SPRINT:
+		return ga.Lines >= SPRINT_LINES
+	case ULTRA:
+		return ga.PlayTime() >= ga.UltraTime
+	}
+	return false
+}
+
+func (ga *Game) finish() {
+	//--------------------------------------------------
+	for ; ga.nbCompledLines > 0; ga.nbCompledLines-- {
+		ga.EraseFirstCompletedLine()
+	}
+	ga.Mode = FINISHED
+	ga.CurTetromino = nil
+	ga.events = append(ga.events, EV_FINISHED)
+}
+
+// GhostTetromino returns a copy of the falling tetromino moved down to the
+// position where it would lock.
+func (ga *Game) GhostTetromino() *Tetromino {
+	//--------------------------------------------------
+	if ga.CurTetromino == nil {
+		return nil
+	}
+	ghost := *ga.CurTetromino
+	ga.land(&ghost)
+	return &ghost
+}
+
+// land moves te straight down until it rests on the stack or the floor.
+func (ga *Game) land(te *Tetromino) {
+	//--------------------------------------------------
+	for {
+		te.Y--
+		if te.HitGround(ga.Board) || te.IsOutBottomLimit() {
+			te.Y++
+			return
+		}
+	}
+}
+
+func (ga *Game) fallDown(nbSteps int) {
+	//--------------------------------------------------
+	var nbMoved int32
+	for iOffSet := 0; iOffSet < nbSteps; iOffSet++ {
+		//-- Move down to check
+		ga.CurTetromino.Y--
+		if ga.CurTetromino.HitGround(ga.Board) || ga.CurTetromino.IsOutBottomLimit() {
+			ga.CurTetromino.Y++
+			break
+		}
+		nbMoved++
+		if ga.CurTetromino.Y < ga.lowestY {
+			ga.lowestY = ga.CurTetromino.Y
+			ga.nbLockResets = 0
+		}
+	}
+	if nbMoved == 0 {
+		return
+	}
+	ga.fLastRotation = false
+	if ga.fSonicDrop || ga.fFastDown {
+		ga.scoreDrop(nbMoved, SOFT_DROP_POINTS)
+	}
+}
+
+// hardDrop moves the falling tetromino down to where it lands and locks it
+// in the same tick.
+func (ga *Game) hardDrop() {
+	//--------------------------------------------------
+	ga.fHardDrop = false
+	te := ga.CurTetromino
+	ghost := ga.GhostTetromino()
+	if nbUnits := te.Y - ghost.Y; nbUnits > 0 {
+		te.Y = ghost.Y
+		ga.fLastRotation = false
+		ga.scoreDrop(nbUnits, HARD_DROP_POINTS)
+	}
+	ga.lockTetromino()
+}
+
+func (ga *Game) updateLock(dt time.Duration) {
+	//--------------------------------------------------
+	te := ga.CurTetromino
+	te.Y--
+	ga.fGrounded = te.HitGround(ga.Board) || te.IsOutBottomLimit()
+	te.Y++
+	if !ga.fGrounded {
+		ga.lockElapsed = 0
+		return
+	}
+
+	ga.lockElapsed += dt
+	if ga.lockElapsed >= ga.LockDelay ||
+		(ga.fFastDown && ga.SoftDropLock) || (ga.fSonicDrop && ga.SonicDropLock) {
+		ga.lockTetromino()
+	}
+}
+
+// resetLockDelay restarts the lock timer after a successful move or
+// rotation on the ground, at most MaxLockResets times per piece.
+func (ga *Game) resetLockDelay() {
+	//--------------------------------------------------
+	if ga.fGrounded && ga.nbLockResets < ga.MaxLockResets {
+		ga.lockElapsed = 0
+		ga.nbLockResets++
+	}
+}
+
+func (ga *Game) lockTetromino() {
+	//--------------------------------------------------
+	ga.FreezeTetromino(ga.CurTetromino)
+	ga.NewTetromino()
+	ga.fSonicDrop = false
+	ga.elapsedV = 0
+}
+
+func (ga *Game) FreezeTetromino(tetro *Tetromino) {
+	//--------------------------------------------------
+	if tetro != nil {
+		spin := ga.tSpinOf(tetro)
+		offSet := int32(NB_ROWS * CELL_SIZE)
+		ix := (tetro.X + 1) / CELL_SIZE
+		iy := (offSet - tetro.Y + 1) / CELL_SIZE
+		for _, v := range tetro.V {
+			x := v.X + ix
+			y := iy - v.Y
+			if x >= 0 && x < NB_COLUMNS && y >= 0 && y < NB_ROWS {
+				ga.Board[y*NB_COLUMNS+x] = int(tetro.Typ)
+			}
+		}
+		ga.fHoldUsed = false
+		ga.events = append(ga.events, EV_LOCKED)
+		//--
+		ga.nbCompledLines = ga.ComputeCompletedLines()
+		ga.scoreLock(ga.nbCompledLines, spin, ga.nbCompledLines > 0 && ga.IsPerfectClear())
+		if ga.nbCompledLines > 0 {
+			ga.Lines += ga.nbCompledLines
+			if ga.LinesPerLevel > 0 {
+				ga.Level = max(ga.Level, ga.StartLevel+ga.Lines/ga.LinesPerLevel)
+			}
+			for len(ga.Splits) < ga.Lines/SPLIT_LINES {
+				ga.Splits = append(ga.Splits, ga.PlayTime())
+			}
+		}
+
+	}
+}
+
+func (ga *Game) ComputeCompletedLines() int {
+	//--------------------------------------------------
+	nbLines := 0
+	fCompleted := false
+	for r := 0; r < NB_ROWS; r++ {
+		fCompleted = true
+		for c := 0; c < NB_COLUMNS; c++ {
+			if ga.Board[r*NB_COLUMNS+c] == 0 {
+				fCompleted = false
+				break
+			}
+		}
+		if fCompleted {
+			nbLines++
+		}
+	}
+	return nbLines
+}
+
+func (ga *Game) EraseFirstCompletedLine() {
+	//--------------------------------------------------
+	fCompleted := false
+	for r := 0; r < NB_ROWS; r++ {
+		fCompleted = true
+		for c := 0; c < NB_COLUMNS; c++ {
+			if ga.Board[r*NB_COLUMNS+c] == 0 {
+				fCompleted = false
+				break
+			}
+		}
+		if fCompleted {
+			//-- Décaler d'une ligne le plateau
+			for r1 := r; r1 > 0; r1-- {
+				for c1 := 0; c1 < NB_COLUMNS; c1++ {
+					ga.Board[r1*NB_COLUMNS+c1] = ga.Board[(r1-1)*NB_COLUMNS+c1]
+				}
+			}
+			return
+		}
+	}
+}
+
+func (ga *Game) ClearBoard() {
+	//--------------------------------------------------
+	for i := 0; i < NB_ROWS*NB_COLUMNS; i++ {
+		ga.Board[i] = 0
+	}
+
+}
+
+func (ga *Game) IsGameOver() bool {
+	//------------------------------------------------------
+	for i := 0; i < NB_COLUMNS; i++ {
+		if ga.Board[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSonicDropSpeed(t *testing.T) {
+	for _, nbTicks := range []int{1, 2, 12, 60} {
+		ga := GameNew(1)
+		ga.Start()
+		y0 := ga.CurTetromino.Y
+		ga.Step([]InputEvent{{Action: SONIC_DROP, Pressed: true}})
+		for i := 1; i < nbTicks; i++ {
+			ga.Step(nil)
+		}
+		want := int32(time.Duration(nbTicks)*TICK/sonicDropStep) * SONIC_DROP_UNITS
+		if fallen := y0 - ga.CurTetromino.Y; fallen != want {
+			t.Errorf("%d ticks: fell %d units, want %d", nbTicks, fallen, want)
+		}
+	}
+}
+
+// Update hands the inputs of a frame to its first tick, however many ticks
+// the frame runs: stepped tick by tick with the inputs on those ticks, the
+// game plays the same.
+func TestUpdateFrameRate(t *testing.T) {
+	const nbTicks = 4000
+	rnd := rand.New(rand.NewSource(1))
+	frameRates := []struct {
+		name  string
+		frame func() time.Duration
+	}{
+		{"60Hz", func() time.Duration { return time.Second / 60 }},
+		{"144Hz", func() time.Duration { return time.Second / 144 }},
+		{"jittered", func() time.Duration { return time.Duration(1+rnd.Intn(50)) * time.Millisecond }},
+	}
+	for _, fr := range frameRates {
+		ga := GameNew(7)
+		ga.Start()
+		bo := BotNew()
+		script := map[int][]InputEvent{}
+		nbLate := 0
+		for ga.Tick < nbTicks && ga.Mode == PLAY {
+			dt := fr.frame()
+			inputs := bo.Inputs(ga, dt)
+			script[ga.Tick] = append(script[ga.Tick], inputs...)
+			tick := ga.Tick
+			ga.Update(dt, inputs)
+			if len(inputs) > 0 && ga.Tick-tick > 1 {
+				nbLate++
+			}
+		}
+		if fr.name != "144Hz" && nbLate == 0 {
+			t.Errorf("%s: no inputs in frames of several ticks", fr.name)
+		}
+
+		ref := GameNew(7)
+		ref.Start()
+		for ref.Tick < ga.Tick && ref.Mode == PLAY {
+			ref.Step(script[ref.Tick])
+		}
+		if ga.Tick != ref.Tick || ga.CurScore != ref.CurScore || ga.Lines != ref.Lines ||
+			!reflect.DeepEqual(ga.Board, ref.Board) {
+			t.Errorf("%s: tick %d/%d, score %d/%d, lines %d/%d", fr.name,
+				ga.Tick, ref.Tick, ga.CurScore, ref.CurScore, ga.Lines, ref.Lines)
+		}
+	}
+}
+
+func TestNoLevelUp(t *testing.T) {
+	for _, linesPerLevel := range []int{0, 1} {
+		ga := GameNew(1)
+		ga.LinesPerLevel = linesPerLevel
+		ga.Start()
+		ga.Board = boardOf(".XXXXXXXXXXX")
+		ga.FreezeTetromino(TetrominoNew(3, 0, (NB_ROWS-18)*CELL_SIZE))
+		want := ga.StartLevel + linesPerLevel
+		if ga.Lines != 1 || ga.Level != want {
+			t.Errorf("%d lines per level: %d lines, level %d, want 1 line, level %d",
+				linesPerLevel, ga.Lines, ga.Level, want)
+		}
+	}
+}
+
+// Moving a grounded tetromino restarts its lock delay MaxLockResets times;
+// it locks a LockDelay after the last of those moves.
+func TestLockResetsCap(t *testing.T) {
+	const moveTicks = 24
+	for _, maxResets := range []int{0, 5, 15} {
+		ga := GameNew(1)
+		ga.MaxLockResets = maxResets
+		ga.Start()
+		te := ga.CurTetromino
+		ga.land(te)
+
+		lastReset := 0
+		nbMoves := 0
+		for ga.CurTetromino == te && ga.Tick < 10000 {
+			var inputs []InputEvent
+			if ga.Tick%moveTicks == moveTicks/2 {
+				action := MOVE_LEFT
+				if nbMoves%2 == 1 {
+					action = MOVE_RIGHT
+				}
+				inputs = []InputEvent{{Action: action, Pressed: true}, {Action: action, Pressed: false}}
+				nbMoves++
+				if nbMoves <= maxResets {
+					lastReset = ga.Tick
+				}
+			}
+			ga.Step(inputs)
+		}
+
+		lockTicks := int(ga.LockDelay / TICK)
+		if ga.CurTetromino == te {
+			t.Errorf("%d resets: never locked", maxResets)
+		} else if lockTick := ga.Tick - 1; lockTick < lastReset+lockTicks-1 || lockTick > lastReset+lockTicks {
+			t.Errorf("%d resets: locked on tick %d, last reset on tick %d", maxResets, lockTick, lastReset)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"math"
+	"time"
+)
+
+// GravityCurve_t returns the time a tetromino takes to fall one cell at the
+// given level. Levels start at 1.
+type GravityCurve_t func(level int) time.Duration
+
+// GravityType names a gravity curve, so that it can be saved with Config.
+type GravityType int
+
+const (
+	GUIDELINE_GRAVITY GravityType = iota
+	NES_GRAVITY
+	NB_GRAVITIES
+)
+
+var gravityCurves = [NB_GRAVITIES]GravityCurve_t{GuidelineGravity, NESGravity}
+
+var gravityNames = [NB_GRAVITIES]string{"GUIDELINE", "NES"}
+
+func (gt GravityType) String() string {
+	return gravityNames[gt]
+}
+
+// Frames per cell of the NES version, from its level 0 to level 29.
+var nesFramesPerCell = []int{
+	48, 43, 38, 33, 28, 23, 18, 13, 8, 6,
+	5, 5, 5, 4, 4, 4, 3, 3, 3, 2,
+	2, 2, 2, 2, 2, 2, 2, 2, 2, 1,
+}
+
+// GuidelineGravity follows (0.8 - (level-1)*0.007)^(level-1) seconds per
+// cell, capped at level 20.
+func GuidelineGravity(level int) time.Duration {
+	l := float64(min(max(level, 1), 20) - 1)
+	return time.Duration(math.Pow(0.8-l*0.007, l) * float64(time.Second))
+}
+
+// NESGravity plays level 1 at the speed of NES level 0.
+func NESGravity(level int) time.Duration {
+	id := min(max(level, 1), len(nesFramesPerCell)) - 1
+	return time.Duration(float64(nesFramesPerCell[id]) * float64(time.Second) / 60.0988)
+}
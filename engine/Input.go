@@ -0,0 +1,32 @@
+package engine
+
+type Action int
+
+const (
+	MOVE_LEFT Action = iota
+	MOVE_RIGHT
+	ROTATE_LEFT
+	FAST_DOWN
+	SONIC_DROP
+	PAUSE
+	HOLD
+	HARD_DROP
+	ROTATE_RIGHT
+	ROTATE_180
+)
+
+// InputEvent is a press or a release of an Action, as fed to Game.Update.
+type InputEvent struct {
+	Action  Action
+	Pressed bool
+}
+
+type Event int
+
+const (
+	EV_LOCKED Event = iota
+	EV_LINE_ERASED
+	EV_GAMEOVER
+	EV_FINISHED
+	EV_CLEAR
+)
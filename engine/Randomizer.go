@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Randomizer deals the sequence of tetromino types, from 1 to 7. All the
+// randomness comes from the rand.Rand it is built with, so that a seed
+// gives the same sequence every time.
+type Randomizer interface {
+	Next() int32
+}
+
+type RandomizerType int
+
+const (
+	BAG_7 RandomizerType = iota
+	BAG_14
+	PURE_RANDOM
+	NES_REROLL
+	TGM_HISTORY
+	NB_RANDOMIZERS
+)
+
+var randomizerNames = [NB_RANDOMIZERS]string{"7-BAG", "14-BAG", "RANDOM", "NES", "TGM"}
+
+func (rt RandomizerType) String() string {
+	return randomizerNames[rt]
+}
+
+// RandomizerTypeOf looks a randomizer up by name, case ignored.
+func RandomizerTypeOf(name string) (RandomizerType, bool) {
+	for rt, s := range randomizerNames {
+		if strings.EqualFold(s, name) {
+			return RandomizerType(rt), true
+		}
+	}
+	return 0, false
+}
+
+func RandomizerNew(typ RandomizerType, rnd *rand.Rand) Randomizer {
+	switch typ {
+	case BAG_14:
+		return BagRandomizerNew(rnd, 2)
+	case PURE_RANDOM:
+		return &PureRandomizer{rnd: rnd}
+	case NES_REROLL:
+		return &NESRandomizer{rnd: rnd}
+	case TGM_HISTORY:
+		return TGMRandomizerNew(rnd)
+	}
+	return BagRandomizerNew(rnd, 1)
+}
+
+// BagRandomizer deals shuffled bags holding each type nbCopies times.
+type BagRandomizer struct {
+	rnd             *rand.Rand
+	idtetrominosBag int
+	tetrominosBag   []int32
+}
+
+func BagRandomizerNew(rnd *rand.Rand, nbCopies int) *BagRandomizer {
+	ra := &BagRandomizer{rnd: rnd}
+	for i := 0; i < nbCopies; i++ {
+		ra.tetrominosBag = append(ra.tetrominosBag, 1, 2, 3, 4, 5, 6, 7)
+	}
+	ra.idtetrominosBag = len(ra.tetrominosBag)
+	return ra
+}
+
+func (ra *BagRandomizer) Next() int32 {
+	//--------------------------------------------------
+	if ra.idtetrominosBag >= len(ra.tetrominosBag) {
+		//-- Fisher-Yates shuffle
+		for i := len(ra.tetrominosBag) - 1; i > 0; i-- {
+			j := ra.rnd.Intn(i + 1)
+			ra.tetrominosBag[i], ra.tetrominosBag[j] = ra.tetrominosBag[j], ra.tetrominosBag[i]
+		}
+		ra.idtetrominosBag = 0
+	}
+	ityp := ra.tetrominosBag[ra.idtetrominosBag]
+	ra.idtetrominosBag++
+	return ityp
+}
+
+type PureRandomizer struct {
+	rnd *rand.Rand
+}
+
+func (ra *PureRandomizer) Next() int32 {
+	return 1 + int32(ra.rnd.Intn(7))
+}
+
+// NESRandomizer rolls an 8 sided die: on the eighth face or on a repeat of
+// the previous type it rolls once more among the 7 types, and keeps that.
+type NESRandomizer struct {
+	rnd  *rand.Rand
+	prev int32
+}
+
+func (ra *NESRandomizer) Next() int32 {
+	//--------------------------------------------------
+	ityp := 1 + int32(ra.rnd.Intn(8))
+	if ityp == 8 || ityp == ra.prev {
+		ityp = 1 + int32(ra.rnd.Intn(7))
+	}
+	ra.prev = ityp
+	return ityp
+}
+
+// TGMRandomizer rerolls up to 6 times a type found among the last 4 dealt.
+// The history starts full of S and Z, and the first piece is never an S,
+// a Z or an O.
+type TGMRandomizer struct {
+	rnd     *rand.Rand
+	history [4]int32
+	fFirst  bool
+}
+
+const TGM_NB_ROLLS = 6
+
+func TGMRandomizerNew(rnd *rand.Rand) *TGMRandomizer {
+	return &TGMRandomizer{rnd: rnd, history: [4]int32{2, 1, 2, 1}, fFirst: true}
+}
+
+func (ra *TGMRandomizer) Next() int32 {
+	//--------------------------------------------------
+	var ityp int32
+	if ra.fFirst {
+		ra.fFirst = false
+		//-- I, T, J or L
+		ityp = []int32{3, 4, 6, 7}[ra.rnd.Intn(4)]
+	} else {
+		for i := 0; i < TGM_NB_ROLLS; i++ {
+			ityp = 1 + int32(ra.rnd.Intn(7))
+			if !ra.inHistory(ityp) {
+				break
+			}
+		}
+	}
+	copy(ra.history[1:], ra.history[:3])
+	ra.history[0] = ityp
+	return ityp
+}
+
+func (ra *TGMRandomizer) inHistory(ityp int32) bool {
+	for _, h := range ra.history {
+		if h == ityp {
+			return true
+		}
+	}
+	return false
+}
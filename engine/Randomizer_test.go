@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+const nbDraws = 70000
+
+// 99.99th percentile of the chi-squared distribution with 6 degrees of
+// freedom: a fair randomizer fails once in 10000 seeds.
+const chi2Max6 = 27.86
+
+func draw(typ RandomizerType, seed int64, n int) []int32 {
+	ra := RandomizerNew(typ, rand.New(rand.NewSource(seed)))
+	seq := make([]int32, n)
+	for i := range seq {
+		seq[i] = ra.Next()
+	}
+	return seq
+}
+
+func chi2(counts []int, n int) float64 {
+	expected := float64(n) / float64(len(counts))
+	var sum float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		sum += d * d / expected
+	}
+	return sum
+}
+
+func TestRandomizerDistribution(t *testing.T) {
+	for rt := RandomizerType(0); rt < NB_RANDOMIZERS; rt++ {
+		counts := make([]int, 7)
+		for _, ityp := range draw(rt, 1, nbDraws) {
+			if ityp < 1 || ityp > 7 {
+				t.Fatalf("%s: type %d out of range", rt, ityp)
+			}
+			counts[ityp-1]++
+		}
+		if x := chi2(counts, nbDraws); x > chi2Max6 {
+			t.Errorf("%s: counts %v are not uniform, chi2 = %.1f", rt, counts, x)
+		}
+	}
+}
+
+func TestRandomizerSeed(t *testing.T) {
+	for rt := RandomizerType(0); rt < NB_RANDOMIZERS; rt++ {
+		a, b, c := draw(rt, 7, 100), draw(rt, 7, 100), draw(rt, 8, 100)
+		fSame := true
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("%s: same seed, different sequences", rt)
+			}
+			fSame = fSame && a[i] == c[i]
+		}
+		if fSame {
+			t.Errorf("%s: different seeds, same sequence", rt)
+		}
+	}
+}
+
+func TestBagRandomizerBags(t *testing.T) {
+	for _, tc := range []struct {
+		typ      RandomizerType
+		nbCopies int
+	}{{BAG_7, 1}, {BAG_14, 2}} {
+		size := 7 * tc.nbCopies
+		seq := draw(tc.typ, 1, size*1000)
+		for i := 0; i < len(seq); i += size {
+			counts := make([]int, 7)
+			for _, ityp := range seq[i : i+size] {
+				counts[ityp-1]++
+			}
+			for _, c := range counts {
+				if c != tc.nbCopies {
+					t.Fatalf("%s: bag %v", tc.typ, seq[i:i+size])
+				}
+			}
+		}
+	}
+}
+
+// With a uniform shuffle each type is equally likely at each place of
+// the bag.
+func TestBagRandomizerShuffle(t *testing.T) {
+	seq := draw(BAG_7, 1, nbDraws)
+	for ityp := int32(1); ityp <= 7; ityp++ {
+		counts := make([]int, 7)
+		for i, v := range seq {
+			if v == ityp {
+				counts[i%7]++
+			}
+		}
+		if x := chi2(counts, nbDraws/7); x > chi2Max6 {
+			t.Errorf("type %d: places %v are not uniform, chi2 = %.1f", ityp, counts, x)
+		}
+	}
+}
+
+func repeatRate(seq []int32) float64 {
+	nbRepeats := 0
+	for i := 1; i < len(seq); i++ {
+		if seq[i] == seq[i-1] {
+			nbRepeats++
+		}
+	}
+	return float64(nbRepeats) / float64(len(seq)-1)
+}
+
+func TestRandomizerRepeats(t *testing.T) {
+	for _, tc := range []struct {
+		typ      RandomizerType
+		min, max float64
+	}{
+		//-- 1/7
+		{PURE_RANDOM, 0.135, 0.15},
+		//-- 1/8 * 1/7 for a repeat rerolled into itself, twice
+		{NES_REROLL, 0.032, 0.04},
+		//-- At most (4/7)^6 / 4
+		{TGM_HISTORY, 0, 0.012},
+	} {
+		if r := repeatRate(draw(tc.typ, 1, nbDraws)); r < tc.min || r > tc.max {
+			t.Errorf("%s: repeat rate %.4f out of [%.3f, %.3f]", tc.typ, r, tc.min, tc.max)
+		}
+	}
+}
+
+func TestTGMRandomizerFirst(t *testing.T) {
+	for seed := int64(0); seed < 1000; seed++ {
+		switch first := draw(TGM_HISTORY, seed, 1)[0]; first {
+		case 1, 2, 5:
+			t.Fatalf("seed %d: first piece %d is an S, Z or O", seed, first)
+		}
+	}
+}
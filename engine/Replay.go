@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"os"
+)
+
+const REPLAY_VERSION = 1
+
+type ReplayInput struct {
+	Tick  int
+	Input InputEvent
+}
+
+// Replay is everything needed to play a game again: its seed and rules,
+// its length in ticks and the inputs applied on each tick.
+type Replay struct {
+	Version int
+	Seed    int64
+	Config  Config
+	NbTicks int
+	Inputs  []ReplayInput
+}
+
+// ReplayNew starts a recording of the game about to be played. Set it as
+// Game.Recorder once the game is started.
+func ReplayNew(ga *Game) *Replay {
+	return &Replay{Version: REPLAY_VERSION, Seed: ga.Seed, Config: ga.Config}
+}
+
+func (re *Replay) record(tick int, inputs []InputEvent) {
+	//--------------------------------------------------
+	for _, in := range inputs {
+		re.Inputs = append(re.Inputs, ReplayInput{Tick: tick, Input: in})
+	}
+}
+
+// saveGob writes v gzipped, in gob, as replays and snapshots are saved.
+func saveGob(fileName string, v any) error {
+	//--------------------------------------------------
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	if err := gob.NewEncoder(zw).Encode(v); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func loadGob(fileName string, v any) error {
+	//--------------------------------------------------
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(zr).Decode(v)
+}
+
+func (re *Replay) Save(fileName string) error {
+	return saveGob(fileName, re)
+}
+
+func LoadReplay(fileName string) (*Replay, error) {
+	//--------------------------------------------------
+	re := &Replay{}
+	if err := loadGob(fileName, re); err != nil {
+		return nil, err
+	}
+	if re.Version != REPLAY_VERSION {
+		return nil, fmt.Errorf("%s: unsupported replay version %d", fileName, re.Version)
+	}
+	if err := re.Config.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return re, nil
+}
+
+type ReplayPlayer struct {
+	replay  *Replay
+	game    *Game
+	idInput int
+	inputs  []InputEvent
+}
+
+// ReplayPlayerNew restarts ga with the seed and rules of the replay.
+func ReplayPlayerNew(re *Replay, ga *Game) *ReplayPlayer {
+	//--------------------------------------------------
+	ga.Config = re.Config
+	ga.Seed = re.Seed
+	ga.Recorder = nil
+	ga.Start()
+	return &ReplayPlayer{replay: re, game: ga}
+}
+
+func (rp *ReplayPlayer) Done() bool {
+	return rp.game.Tick >= rp.replay.NbTicks || rp.game.Mode != PLAY
+}
+
+// Step plays the next recorded tick.
+func (rp *ReplayPlayer) Step() []Event {
+	//--------------------------------------------------
+	if rp.Done() {
+		return nil
+	}
+	tick := rp.game.Tick
+	rp.inputs = rp.inputs[:0]
+	for ; rp.idInput < len(rp.replay.Inputs) && rp.replay.Inputs[rp.idInput].Tick == tick; rp.idInput++ {
+		rp.inputs = append(rp.inputs, rp.replay.Inputs[rp.idInput].Input)
+	}
+	return rp.game.Step(rp.inputs)
+}
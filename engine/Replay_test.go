@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// A pause neither lengthens the replay nor shows in its playback.
+func TestReplayPause(t *testing.T) {
+	const nbTicks, pauseTick, pauseLength = 3000, 1000, 100
+	ga := GameNew(3)
+	ga.Start()
+	ga.Recorder = ReplayNew(ga)
+	bo := BotNew()
+	for tick := 0; tick < nbTicks && ga.Mode == PLAY; tick++ {
+		if tick == pauseTick {
+			ga.Step([]InputEvent{{Action: PAUSE, Pressed: true}})
+			for i := 0; i < pauseLength; i++ {
+				ga.Step([]InputEvent{{Action: MOVE_LEFT, Pressed: false}})
+			}
+			ga.Step([]InputEvent{{Action: PAUSE, Pressed: true}})
+		}
+		ga.Step(bo.Inputs(ga, TICK))
+	}
+
+	if ga.Recorder.NbTicks != ga.Tick {
+		t.Errorf("replay lasts %d ticks, game %d", ga.Recorder.NbTicks, ga.Tick)
+	}
+	for _, ri := range ga.Recorder.Inputs {
+		if ri.Input.Action == PAUSE {
+			t.Fatalf("pause recorded on tick %d", ri.Tick)
+		}
+	}
+
+	played := GameNew(0)
+	rp := ReplayPlayerNew(ga.Recorder, played)
+	for !rp.Done() {
+		rp.Step()
+	}
+	if played.Tick != ga.Tick || played.CurScore != ga.CurScore || !reflect.DeepEqual(played.Board, ga.Board) {
+		t.Errorf("replay differs: tick %d/%d, score %d/%d", played.Tick, ga.Tick, played.CurScore, ga.CurScore)
+	}
+}
+
+// A saved replay brings back its rules, whatever those of the game it is
+// played on.
+func TestReplayRoundTrip(t *testing.T) {
+	const nbTicks = 4000
+	ga := GameNew(11)
+	ga.RandomizerType = TGM_HISTORY
+	ga.KickTable = CLASSIC_KICKS
+	ga.Gravity = NES_GRAVITY
+	ga.StartLevel = 10
+	ga.Start()
+	ga.Recorder = ReplayNew(ga)
+	bo := BotNew()
+	for ga.Tick < nbTicks && ga.Mode == PLAY {
+		ga.Step(bo.Inputs(ga, TICK))
+	}
+
+	fileName := filepath.Join(t.TempDir(), "game.rpl")
+	if err := ga.Recorder.Save(fileName); err != nil {
+		t.Fatal(err)
+	}
+	re, err := LoadReplay(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.Config != ga.Config {
+		t.Errorf("loaded config %+v, want %+v", re.Config, ga.Config)
+	}
+
+	played := GameNew(0)
+	rp := ReplayPlayerNew(re, played)
+	for !rp.Done() {
+		rp.Step()
+	}
+	if played.Tick != ga.Tick || played.CurScore != ga.CurScore || played.Lines != ga.Lines ||
+		!reflect.DeepEqual(played.Board, ga.Board) {
+		t.Errorf("replay differs: tick %d/%d, score %d/%d, lines %d/%d",
+			played.Tick, ga.Tick, played.CurScore, ga.CurScore, played.Lines, ga.Lines)
+	}
+}
+
+func TestReplayUnknownRules(t *testing.T) {
+	ga := GameNew(1)
+	ga.Start()
+	re := ReplayNew(ga)
+	re.Config.Gravity = NB_GRAVITIES
+	fileName := filepath.Join(t.TempDir(), "game.rpl")
+	if err := re.Save(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadReplay(fileName); err == nil {
+		t.Error("replay with an unknown gravity loaded")
+	}
+}
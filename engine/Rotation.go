@@ -0,0 +1,150 @@
+package engine
+
+const (
+	ROT_0 int32 = iota
+	ROT_R
+	ROT_2
+	ROT_L
+)
+
+// KickTable_t returns the offsets, in cells, tried in order when a
+// tetromino of type typ rotates from state from to state to. The first
+// offset that does not collide is kept; if none fits the rotation fails.
+type KickTable_t func(typ, from, to int32) []Vector2i
+
+// KickTableType names a kick table, so that it can be saved with Config.
+type KickTableType int
+
+const (
+	SRS_KICKS KickTableType = iota
+	CLASSIC_KICKS
+	NB_KICK_TABLES
+)
+
+var kickTables = [NB_KICK_TABLES]KickTable_t{SRSKickTable, ClassicKickTable}
+
+var kickTableNames = [NB_KICK_TABLES]string{"SRS", "CLASSIC"}
+
+func (kt KickTableType) String() string {
+	return kickTableNames[kt]
+}
+
+var kicksJLSTZ = [4][4][]Vector2i{
+	ROT_0: {
+		ROT_R: {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}},
+		ROT_L: {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},
+	},
+	ROT_R: {
+		ROT_0: {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},
+		ROT_2: {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},
+	},
+	ROT_2: {
+		ROT_R: {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}},
+		ROT_L: {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},
+	},
+	ROT_L: {
+		ROT_2: {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},
+		ROT_0: {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},
+	},
+}
+
+var kicksI = [4][4][]Vector2i{
+	ROT_0: {
+		ROT_R: {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}},
+		ROT_L: {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}},
+	},
+	ROT_R: {
+		ROT_0: {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}},
+		ROT_2: {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}},
+	},
+	ROT_2: {
+		ROT_R: {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}},
+		ROT_L: {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}},
+	},
+	ROT_L: {
+		ROT_2: {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}},
+		ROT_0: {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}},
+	},
+}
+
+var noKick = []Vector2i{{0, 0}}
+
+// SRSKickTable is the Super Rotation System wall kick data.
+func SRSKickTable(typ, from, to int32) []Vector2i {
+	var kicks []Vector2i
+	switch typ {
+	case 3:
+		kicks = kicksI[from][to]
+	case 5:
+		kicks = noKick
+	default:
+		kicks = kicksJLSTZ[from][to]
+	}
+	if kicks == nil {
+		return noKick
+	}
+	return kicks
+}
+
+// ClassicKickTable only accepts a rotation in place.
+func ClassicKickTable(typ, from, to int32) []Vector2i {
+	return noKick
+}
+
+func (ga *Game) RotateTetromino(fRight bool) bool {
+	//--------------------------------------------------
+	if fRight {
+		return ga.rotateTetromino((*Tetromino).RotateRight)
+	}
+	return ga.rotateTetromino((*Tetromino).RotateLeft)
+}
+
+// RotateTetromino180 turns the tetromino twice at once. The kick tables
+// have no entry for it, so it only succeeds in place.
+func (ga *Game) RotateTetromino180() bool {
+	//--------------------------------------------------
+	return ga.rotateTetromino(func(te *Tetromino) {
+		te.RotateRight()
+		te.RotateRight()
+	})
+}
+
+func (ga *Game) rotateTetromino(turn func(te *Tetromino)) bool {
+	//--------------------------------------------------
+	te := ga.CurTetromino
+	if te == nil {
+		return false
+	}
+	kick, ok := ga.turnTetromino(te, turn)
+	if !ok {
+		return false
+	}
+	ga.fLastRotation = true
+	ga.lastKick = kick
+	ga.resetLockDelay()
+	return true
+}
+
+// turnTetromino turns te and moves it by the first kick of the table that
+// makes it fit on the board. It returns the index of that kick, or false
+// with te left as it was.
+func (ga *Game) turnTetromino(te *Tetromino, turn func(te *Tetromino)) (int, bool) {
+	//--------------------------------------------------
+	if te.Typ == 5 {
+		return 0, false
+	}
+
+	backup := *te
+	turn(te)
+
+	for i, k := range kickTables[ga.KickTable](te.Typ, backup.Rot, te.Rot) {
+		te.X = backup.X + k.X*CELL_SIZE
+		te.Y = backup.Y + k.Y*CELL_SIZE
+		if !te.IsOutBoardLimit() && !te.HitGround(ga.Board) {
+			return i, true
+		}
+	}
+
+	*te = backup
+	return 0, false
+}
@@ -0,0 +1,65 @@
+package engine
+
+import "testing"
+
+// pieceAt is a tetromino centered on column c and row r, counted from the
+// top, turned right nbTurns times from its spawn state. It sits as low in
+// the row as a landed tetromino.
+func pieceAt(typ, c, r int32, nbTurns int) *Tetromino {
+	te := TetrominoNew(typ, c*CELL_SIZE, (NB_ROWS-r)*CELL_SIZE+1)
+	for i := 0; i < nbTurns; i++ {
+		te.RotateRight()
+	}
+	return te
+}
+
+func TestWallKicks(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      []string
+		te        *Tetromino
+		kickTable KickTableType
+		ok        bool
+		kick      int
+		dx, dy    int32
+	}{
+		{"I against the left wall", nil, pieceAt(3, 0, 10, 0), SRS_KICKS, true, 1, 1, 0},
+		{"I against the right wall", nil, pieceAt(3, 11, 10, 0), SRS_KICKS, true, 2, -2, 0},
+		{"I against the wall, no kicks", nil, pieceAt(3, 0, 10, 0), CLASSIC_KICKS, false, 0, 0, 0},
+		{"T against the floor", nil, pieceAt(4, 5, 19, 0), SRS_KICKS, true, 2, -1, 1},
+		{"T against the floor, no kicks", nil, pieceAt(4, 5, 19, 0), CLASSIC_KICKS, false, 0, 0, 0},
+		{"T in place", nil, pieceAt(4, 5, 10, 0), SRS_KICKS, true, 0, 0, 0},
+		{"T boxed in", []string{
+			"XXXXXXXXXXXX",
+			"XXXX...XXXXX",
+			"XXXXX.XXXXXX"}, pieceAt(4, 5, 18, 2), SRS_KICKS, false, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		ga := GameNew(1)
+		ga.KickTable = tt.kickTable
+		ga.Start()
+		ga.Board = boardOf(tt.rows...)
+		ga.CurTetromino = tt.te
+		before := *tt.te
+		ok := ga.RotateTetromino(true)
+		te := ga.CurTetromino
+		if ok != tt.ok {
+			t.Errorf("%s: rotated %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			if *te != before {
+				t.Errorf("%s: failed rotation moved the tetromino", tt.name)
+			}
+			continue
+		}
+		dx, dy := (te.X-before.X)/CELL_SIZE, (te.Y-before.Y)/CELL_SIZE
+		if ga.lastKick != tt.kick || dx != tt.dx || dy != tt.dy || te.Rot != (before.Rot+1)%4 {
+			t.Errorf("%s: kick %d by (%d, %d) to state %d, want kick %d by (%d, %d)", tt.name,
+				ga.lastKick, dx, dy, te.Rot, tt.kick, tt.dx, tt.dy)
+		}
+		if te.IsOutBoardLimit() || te.HitGround(ga.Board) {
+			t.Errorf("%s: kicked into a wall", tt.name)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package engine
+
+// Scoring follows the guideline: every action is worth a base number of
+// points times the level it was made at. Tetrises and T-spins that clear
+// lines are "difficult" and earn 50% more when they follow each other
+// (back-to-back); consecutive clearing locks add a combo bonus.
+
+// ClearType is the kind of a scoring lock.
+type ClearType int
+
+const (
+	CLEAR_SINGLE ClearType = iota
+	CLEAR_DOUBLE
+	CLEAR_TRIPLE
+	CLEAR_TETRIS
+	CLEAR_MINI_TSPIN
+	CLEAR_MINI_TSPIN_SINGLE
+	CLEAR_MINI_TSPIN_DOUBLE
+	CLEAR_TSPIN
+	CLEAR_TSPIN_SINGLE
+	CLEAR_TSPIN_DOUBLE
+	CLEAR_TSPIN_TRIPLE
+	NB_CLEAR_TYPES
+)
+
+var clearTypeNames = [NB_CLEAR_TYPES]string{
+	"SINGLE", "DOUBLE", "TRIPLE", "TETRIS",
+	"MINI T-SPIN", "MINI T-SPIN SINGLE", "MINI T-SPIN DOUBLE",
+	"T-SPIN", "T-SPIN SINGLE", "T-SPIN DOUBLE", "T-SPIN TRIPLE"}
+
+var clearTypePoints = [NB_CLEAR_TYPES]int{
+	100, 300, 500, 800,
+	100, 200, 400,
+	400, 800, 1200, 1600}
+
+// Perfect clear bonus by number of lines, and for a back-to-back tetris.
+var perfectClearPoints = [5]int{0, 800, 1200, 1800, 2000}
+
+const (
+	PERFECT_CLEAR_B2B_TETRIS = 3200
+	COMBO_POINTS             = 50
+	SOFT_DROP_POINTS         = 1
+	HARD_DROP_POINTS         = 2
+)
+
+func (ct ClearType) String() string {
+	return clearTypeNames[ct]
+}
+
+// Difficult tells whether ct keeps a back-to-back chain going.
+func (ct ClearType) Difficult() bool {
+	switch ct {
+	case CLEAR_SINGLE, CLEAR_DOUBLE, CLEAR_TRIPLE, CLEAR_MINI_TSPIN, CLEAR_TSPIN:
+		return false
+	}
+	return true
+}
+
+type TSpin int
+
+const (
+	NO_TSPIN TSpin = iota
+	MINI_TSPIN
+	FULL_TSPIN
+)
+
+// clearTypeOf returns the type of a lock that cleared nbLines lines, or
+// false for a plain lock that scores nothing.
+func clearTypeOf(nbLines int, spin TSpin) (ClearType, bool) {
+	nbLines = min(nbLines, 4)
+	switch spin {
+	case MINI_TSPIN:
+		return CLEAR_MINI_TSPIN + ClearType(min(nbLines, 2)), true
+	case FULL_TSPIN:
+		return CLEAR_TSPIN + ClearType(min(nbLines, 3)), true
+	}
+	if nbLines == 0 {
+		return 0, false
+	}
+	return CLEAR_SINGLE + ClearType(nbLines-1), true
+}
+
+// ClearStat counts the clears of one type and the points they earned.
+type ClearStat struct {
+	Count  int
+	Points int
+}
+
+func (cs *ClearStat) add(points int) {
+	cs.Count++
+	cs.Points += points
+}
+
+// Clear describes the last scoring lock, for display.
+type Clear struct {
+	Type    ClearType
+	B2B     bool
+	Combo   int
+	Perfect bool
+	Points  int
+}
+
+// ComputeScore is the value of a clear of type ct at the current level,
+// bonuses left out.
+func (ga *Game) ComputeScore(ct ClearType) int {
+	return clearTypePoints[ct] * ga.Level
+}
+
+// scoreLock scores the lock of a tetromino that completed nbLines lines.
+// It must run before Level is raised by those lines.
+func (ga *Game) scoreLock(nbLines int, spin TSpin, fPerfect bool) {
+	//--------------------------------------------------
+	ct, ok := clearTypeOf(nbLines, spin)
+	if nbLines == 0 {
+		ga.combo = -1
+	}
+	if !ok {
+		return
+	}
+
+	clear := Clear{Type: ct, Perfect: fPerfect}
+	points := ga.ComputeScore(ct)
+	if nbLines > 0 {
+		if ct.Difficult() {
+			clear.B2B = ga.fB2B
+			ga.fB2B = true
+		} else {
+			ga.fB2B = false
+		}
+		if clear.B2B {
+			points += points / 2
+		}
+		ga.combo++
+		clear.Combo = ga.combo
+		points += COMBO_POINTS * ga.combo * ga.Level
+	}
+	if fPerfect {
+		if clear.B2B && ct == CLEAR_TETRIS {
+			points += PERFECT_CLEAR_B2B_TETRIS * ga.Level
+		} else {
+			points += perfectClearPoints[min(nbLines, 4)] * ga.Level
+		}
+	}
+
+	clear.Points = points
+	ga.LastClear = clear
+	ga.CurScore += points
+	ga.Clears[ct].add(points)
+	ga.events = append(ga.events, EV_CLEAR)
+}
+
+// scoreDrop gives the points of a soft or hard drop each time the
+// tetromino goes down one more cell.
+func (ga *Game) scoreDrop(nbUnits int32, pointsPerCell int) {
+	//--------------------------------------------------
+	ga.dropUnits += nbUnits
+	ga.CurScore += int(ga.dropUnits/CELL_SIZE) * pointsPerCell
+	ga.dropUnits %= CELL_SIZE
+}
+
+// tSpinOf applies the 3-corner rule to a T that is about to lock: its last
+// move must be a rotation and at least 3 of the 4 cells diagonal to its
+// center must be taken, walls and floor included. It is a mini T-spin
+// unless both corners it points to are taken, or the rotation needed the
+// last kick of the table.
+func (ga *Game) tSpinOf(te *Tetromino) TSpin {
+	//--------------------------------------------------
+	if te.Typ != 4 || !ga.fLastRotation {
+		return NO_TSPIN
+	}
+
+	offSet := int32(NB_ROWS * CELL_SIZE)
+	ix := (te.X + 1) / CELL_SIZE
+	iy := (offSet - te.Y + 1) / CELL_SIZE
+	taken := func(c Vector2i) bool {
+		x := ix + c.X
+		y := iy - c.Y
+		if x < 0 || x >= NB_COLUMNS || y >= NB_ROWS {
+			return true
+		}
+		return y >= 0 && ga.Board[y*NB_COLUMNS+x] != 0
+	}
+
+	//-- The T points to the side of its center opposite to its flat side
+	var dir Vector2i
+	for _, v := range te.V {
+		dir.X += v.X
+		dir.Y += v.Y
+	}
+
+	nbCorners, nbFront := 0, 0
+	for _, c := range []Vector2i{{-1, 1}, {1, 1}, {1, -1}, {-1, -1}} {
+		if taken(c) {
+			nbCorners++
+			if c.X*dir.X+c.Y*dir.Y > 0 {
+				nbFront++
+			}
+		}
+	}
+	if nbCorners < 3 {
+		return NO_TSPIN
+	}
+	if nbFront == 2 || ga.lastKick == 4 {
+		return FULL_TSPIN
+	}
+	return MINI_TSPIN
+}
+
+// IsPerfectClear tells whether the board is empty once its completed lines
+// are erased.
+func (ga *Game) IsPerfectClear() bool {
+	//--------------------------------------------------
+	for r := 0; r < NB_ROWS; r++ {
+		nbFilled := 0
+		for c := 0; c < NB_COLUMNS; c++ {
+			if ga.Board[r*NB_COLUMNS+c] != 0 {
+				nbFilled++
+			}
+		}
+		if nbFilled != 0 && nbFilled != NB_COLUMNS {
+			return false
+		}
+	}
+	return true
+}
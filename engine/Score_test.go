@@ -0,0 +1,179 @@
+package engine
+
+import "testing"
+
+// boardOf fills the bottom rows of a board, 'X' for a taken cell.
+func boardOf(rows ...string) []int {
+	board := make([]int, NB_ROWS*NB_COLUMNS)
+	r0 := NB_ROWS - len(rows)
+	for r, row := range rows {
+		for c, ch := range row {
+			if ch == 'X' {
+				board[(r0+r)*NB_COLUMNS+c] = 1
+			}
+		}
+	}
+	return board
+}
+
+// tAt is a T centered on column c and row r, turned right nbTurns times
+// from its spawn state, which points up.
+func tAt(c, r int32, nbTurns int) *Tetromino {
+	return pieceAt(4, c, r, nbTurns)
+}
+
+func TestTSpinOf(t *testing.T) {
+	tests := []struct {
+		name          string
+		rows          []string
+		te            *Tetromino
+		fLastRotation bool
+		lastKick      int
+		want          TSpin
+	}{
+		{"full, pointing down", []string{
+			"X...........",
+			"............",
+			"X.XXXXXXXXXX"}, tAt(1, 18, 2), true, 0, FULL_TSPIN},
+		{"mini, pointing up", []string{
+			"X...........",
+			"............",
+			"X.XXXXXXXXXX"}, tAt(1, 18, 0), true, 0, MINI_TSPIN},
+		{"mini upgraded by the 5th kick", []string{
+			"X...........",
+			"............",
+			"X.XXXXXXXXXX"}, tAt(1, 18, 0), true, 4, FULL_TSPIN},
+		{"mini kept by the 4th kick", []string{
+			"X...........",
+			"............",
+			"X.XXXXXXXXXX"}, tAt(1, 18, 0), true, 3, MINI_TSPIN},
+		{"last move not a rotation", []string{
+			"X...........",
+			"............",
+			"X.XXXXXXXXXX"}, tAt(1, 18, 2), false, 0, NO_TSPIN},
+		{"two corners", []string{
+			"............",
+			"............",
+			"X.XXXXXXXXXX"}, tAt(1, 18, 2), true, 0, NO_TSPIN},
+		{"wall and floor", []string{
+			"............"}, tAt(0, 19, 1), true, 0, MINI_TSPIN},
+		{"wall, floor and front", []string{
+			".X..........",
+			"............"}, tAt(0, 19, 1), true, 0, FULL_TSPIN},
+		{"wall and front corners", []string{
+			".X..........",
+			"............",
+			".X.........."}, tAt(0, 18, 1), true, 0, FULL_TSPIN},
+	}
+	for _, tt := range tests {
+		ga := GameNew(1)
+		ga.Board = boardOf(tt.rows...)
+		ga.fLastRotation = tt.fLastRotation
+		ga.lastKick = tt.lastKick
+		if got := ga.tSpinOf(tt.te); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	ga := GameNew(1)
+	ga.Board = boardOf("X...........", "............", "X.XXXXXXXXXX")
+	ga.fLastRotation = true
+	if got := ga.tSpinOf(TetrominoNew(1, CELL_SIZE, 2*CELL_SIZE)); got != NO_TSPIN {
+		t.Errorf("not a T: got %d", got)
+	}
+}
+
+type lock struct {
+	nbLines  int
+	spin     TSpin
+	fPerfect bool
+}
+
+func TestScoreLock(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		locks []lock
+		want  Clear
+	}{
+		{"single", 1, []lock{{1, NO_TSPIN, false}},
+			Clear{Type: CLEAR_SINGLE, Points: 100}},
+		{"tetris at level 3", 3, []lock{{4, NO_TSPIN, false}},
+			Clear{Type: CLEAR_TETRIS, Points: 2400}},
+		{"T-spin", 1, []lock{{0, FULL_TSPIN, false}},
+			Clear{Type: CLEAR_TSPIN, Points: 400, Combo: 0}},
+		{"T-spin single", 1, []lock{{1, FULL_TSPIN, false}},
+			Clear{Type: CLEAR_TSPIN_SINGLE, Points: 800}},
+		{"T-spin double", 1, []lock{{2, FULL_TSPIN, false}},
+			Clear{Type: CLEAR_TSPIN_DOUBLE, Points: 1200}},
+		{"T-spin triple", 1, []lock{{3, FULL_TSPIN, false}},
+			Clear{Type: CLEAR_TSPIN_TRIPLE, Points: 1600}},
+		{"mini T-spin", 1, []lock{{0, MINI_TSPIN, false}},
+			Clear{Type: CLEAR_MINI_TSPIN, Points: 100}},
+		{"mini T-spin single", 1, []lock{{1, MINI_TSPIN, false}},
+			Clear{Type: CLEAR_MINI_TSPIN_SINGLE, Points: 200}},
+		{"mini T-spin double", 1, []lock{{2, MINI_TSPIN, false}},
+			Clear{Type: CLEAR_MINI_TSPIN_DOUBLE, Points: 400}},
+		{"back-to-back tetris", 1, []lock{{4, NO_TSPIN, false}, {4, NO_TSPIN, false}},
+			Clear{Type: CLEAR_TETRIS, B2B: true, Combo: 1, Points: 1200 + 50}},
+		{"back-to-back T-spin after a tetris", 1, []lock{{4, NO_TSPIN, false}, {1, FULL_TSPIN, false}},
+			Clear{Type: CLEAR_TSPIN_SINGLE, B2B: true, Combo: 1, Points: 1200 + 50}},
+		{"back-to-back over a plain lock", 1, []lock{{4, NO_TSPIN, false}, {0, NO_TSPIN, false}, {4, NO_TSPIN, false}},
+			Clear{Type: CLEAR_TETRIS, B2B: true, Points: 1200}},
+		{"back-to-back over a T-spin without lines", 1, []lock{{4, NO_TSPIN, false}, {0, FULL_TSPIN, false}, {4, NO_TSPIN, false}},
+			Clear{Type: CLEAR_TETRIS, B2B: true, Points: 1200}},
+		{"back-to-back broken by a single", 1, []lock{{4, NO_TSPIN, false}, {1, NO_TSPIN, false}, {4, NO_TSPIN, false}},
+			Clear{Type: CLEAR_TETRIS, Combo: 2, Points: 800 + 100}},
+		{"combo", 1, []lock{{1, NO_TSPIN, false}, {1, NO_TSPIN, false}, {2, NO_TSPIN, false}},
+			Clear{Type: CLEAR_DOUBLE, Combo: 2, Points: 300 + 100}},
+		{"combo reset", 1, []lock{{1, NO_TSPIN, false}, {1, NO_TSPIN, false}, {0, NO_TSPIN, false}, {1, NO_TSPIN, false}},
+			Clear{Type: CLEAR_SINGLE, Points: 100}},
+		{"perfect clear single", 1, []lock{{1, NO_TSPIN, true}},
+			Clear{Type: CLEAR_SINGLE, Perfect: true, Points: 100 + 800}},
+		{"perfect clear tetris", 2, []lock{{4, NO_TSPIN, true}},
+			Clear{Type: CLEAR_TETRIS, Perfect: true, Points: 1600 + 4000}},
+		{"perfect clear back-to-back tetris", 1, []lock{{4, NO_TSPIN, false}, {4, NO_TSPIN, true}},
+			Clear{Type: CLEAR_TETRIS, B2B: true, Combo: 1, Perfect: true, Points: 1200 + 50 + 3200}},
+	}
+	for _, tt := range tests {
+		ga := GameNew(1)
+		ga.StartLevel = tt.level
+		ga.Start()
+		for _, lk := range tt.locks {
+			ga.scoreLock(lk.nbLines, lk.spin, lk.fPerfect)
+		}
+		score := 0
+		for _, cs := range ga.Clears {
+			score += cs.Points
+		}
+		if ga.LastClear != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, ga.LastClear, tt.want)
+		}
+		if ga.CurScore != score {
+			t.Errorf("%s: score %d, want %d", tt.name, ga.CurScore, score)
+		}
+	}
+}
+
+func TestDropPoints(t *testing.T) {
+	ga := GameNew(1)
+	ga.Start()
+
+	//-- Soft drop points come by whole cells, the rest is kept
+	ga.scoreDrop(CELL_SIZE+CELL_SIZE/2, SOFT_DROP_POINTS)
+	if ga.CurScore != 1 {
+		t.Errorf("soft drop of 1.5 cells: score %d, want 1", ga.CurScore)
+	}
+	ga.scoreDrop(CELL_SIZE/2+1, SOFT_DROP_POINTS)
+	if ga.CurScore != 2 {
+		t.Errorf("soft drop of 2 cells: score %d, want 2", ga.CurScore)
+	}
+
+	ga = GameNew(1)
+	ga.Start()
+	nbCells := int((ga.CurTetromino.Y - ga.GhostTetromino().Y) / CELL_SIZE)
+	ga.Step([]InputEvent{{Action: HARD_DROP, Pressed: true}})
+	if want := HARD_DROP_POINTS * nbCells; ga.CurScore != want {
+		t.Errorf("hard drop of %d cells: score %d, want %d", nbCells, ga.CurScore, want)
+	}
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Version 1 is the first snapshot format. Fields added to later versions
+// decode as zero values from older snapshots, which must be filled in by
+// LoadSnapshot; a newer version than the game knows is rejected.
+const SNAPSHOT_VERSION = 1
+
+// Snapshot is the whole state of a game in progress, to resume it later.
+// The randomizer is not saved as such: it is dealt again NbDealt times
+// from the seed, which brings back both its bag and the RNG state.
+type Snapshot struct {
+	Version          int
+	Config           Config
+	Seed             int64
+	NbDealt          int
+	Tick             int
+	Board            []int
+	CurTetromino     *Tetromino
+	NextTetrominos   []*Tetromino
+	HoldTetromino    *Tetromino
+	CurScore         int
+	Level            int
+	Lines            int
+	Splits           []time.Duration
+	Clears           [NB_CLEAR_TYPES]ClearStat
+	LastClear        Clear
+	FPause           bool
+	FHoldUsed        bool
+	FSonicDrop       bool
+	FHardDrop        bool
+	FFastDown        bool
+	FLeftHeld        bool
+	FRightHeld       bool
+	DasDir           int32
+	DasElapsed       time.Duration
+	NbAutoShifts     int
+	Combo            int
+	FB2B             bool
+	FLastRotation    bool
+	LastKick         int
+	DropUnits        int32
+	NbCompletedLines int
+	FGrounded        bool
+	LockElapsed      time.Duration
+	NbLockResets     int
+	LowestY          int32
+	ElapsedV         time.Duration
+	ElapsedR         time.Duration
+	Recorder         *Replay
+}
+
+// Snapshot copies the state of the game. It is only meaningful in PLAY.
+func (ga *Game) Snapshot() *Snapshot {
+	//--------------------------------------------------
+	copyTetromino := func(te *Tetromino) *Tetromino {
+		if te == nil {
+			return nil
+		}
+		c := *te
+		return &c
+	}
+	sn := &Snapshot{
+		Version:          SNAPSHOT_VERSION,
+		Config:           ga.Config,
+		Seed:             ga.Seed,
+		NbDealt:          ga.nbDealt,
+		Tick:             ga.Tick,
+		Board:            append([]int(nil), ga.Board...),
+		CurTetromino:     copyTetromino(ga.CurTetromino),
+		HoldTetromino:    copyTetromino(ga.HoldTetromino),
+		CurScore:         ga.CurScore,
+		Level:            ga.Level,
+		Lines:            ga.Lines,
+		Splits:           append([]time.Duration(nil), ga.Splits...),
+		Clears:           ga.Clears,
+		LastClear:        ga.LastClear,
+		FPause:           ga.FPause,
+		FHoldUsed:        ga.fHoldUsed,
+		FSonicDrop:       ga.fSonicDrop,
+		FHardDrop:        ga.fHardDrop,
+		FFastDown:        ga.fFastDown,
+		FLeftHeld:        ga.fLeftHeld,
+		FRightHeld:       ga.fRightHeld,
+		DasDir:           ga.dasDir,
+		DasElapsed:       ga.dasElapsed,
+		NbAutoShifts:     ga.nbAutoShifts,
+		Combo:            ga.combo,
+		FB2B:             ga.fB2B,
+		FLastRotation:    ga.fLastRotation,
+		LastKick:         ga.lastKick,
+		DropUnits:        ga.dropUnits,
+		NbCompletedLines: ga.nbCompledLines,
+		FGrounded:        ga.fGrounded,
+		LockElapsed:      ga.lockElapsed,
+		NbLockResets:     ga.nbLockResets,
+		LowestY:          ga.lowestY,
+		ElapsedV:         ga.elapsedV,
+		ElapsedR:         ga.elapsedR,
+		Recorder:         ga.Recorder,
+	}
+	for _, te := range ga.NextTetrominos {
+		sn.NextTetrominos = append(sn.NextTetrominos, copyTetromino(te))
+	}
+	return sn
+}
+
+// Restore puts ga back in the state of the snapshot, in PLAY, with its
+// rules.
+func (ga *Game) Restore(sn *Snapshot) {
+	//--------------------------------------------------
+	ga.Config = sn.Config
+	ga.Seed = sn.Seed
+
+	ga.resetState()
+	ga.randomizer = RandomizerNew(ga.RandomizerType, rand.New(rand.NewSource(ga.Seed)))
+	for ga.nbDealt = 0; ga.nbDealt < sn.NbDealt; ga.nbDealt++ {
+		ga.randomizer.Next()
+	}
+
+	ga.Mode = PLAY
+	ga.Tick = sn.Tick
+	copy(ga.Board, sn.Board)
+	ga.CurTetromino = sn.CurTetromino
+	ga.NextTetrominos = append(ga.NextTetrominos[:0], sn.NextTetrominos...)
+	ga.HoldTetromino = sn.HoldTetromino
+	ga.CurScore = sn.CurScore
+	ga.Level = sn.Level
+	ga.Lines = sn.Lines
+	ga.Splits = append(ga.Splits[:0], sn.Splits...)
+	ga.Clears = sn.Clears
+	ga.LastClear = sn.LastClear
+	ga.FPause = sn.FPause
+	ga.fHoldUsed = sn.FHoldUsed
+	ga.fSonicDrop = sn.FSonicDrop
+	ga.fHardDrop = sn.FHardDrop
+	ga.fFastDown = sn.FFastDown
+	ga.fLeftHeld = sn.FLeftHeld
+	ga.fRightHeld = sn.FRightHeld
+	ga.dasDir = sn.DasDir
+	ga.dasElapsed = sn.DasElapsed
+	ga.nbAutoShifts = sn.NbAutoShifts
+	ga.combo = sn.Combo
+	ga.fB2B = sn.FB2B
+	ga.fLastRotation = sn.FLastRotation
+	ga.lastKick = sn.LastKick
+	ga.dropUnits = sn.DropUnits
+	ga.nbCompledLines = sn.NbCompletedLines
+	ga.fGrounded = sn.FGrounded
+	ga.lockElapsed = sn.LockElapsed
+	ga.nbLockResets = sn.NbLockResets
+	ga.lowestY = sn.LowestY
+	ga.elapsedV = sn.ElapsedV
+	ga.elapsedR = sn.ElapsedR
+	ga.Recorder = sn.Recorder
+}
+
+func (sn *Snapshot) Save(fileName string) error {
+	return saveGob(fileName, sn)
+}
+
+func LoadSnapshot(fileName string) (*Snapshot, error) {
+	//--------------------------------------------------
+	sn := &Snapshot{}
+	if err := loadGob(fileName, sn); err != nil {
+		return nil, err
+	}
+	if sn.Version < 1 || sn.Version > SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("%s: unsupported snapshot version %d", fileName, sn.Version)
+	}
+	if err := sn.Config.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	if sn.Recorder != nil && sn.Recorder.Version != REPLAY_VERSION {
+		//-- The game goes on, without a replay of it
+		sn.Recorder = nil
+	}
+	if len(sn.Board) != NB_ROWS*NB_COLUMNS || len(sn.NextTetrominos) != PREVIEW_SIZE || sn.CurTetromino == nil {
+		return nil, fmt.Errorf("%s: corrupted snapshot", fileName)
+	}
+	return sn, nil
+}
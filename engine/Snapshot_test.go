@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Inputs from the spawn of a tetromino, so that the snapshot, taken on
+// tick snapDelay, is in the middle of a sonic drop with moves held.
+const snapDelay = 6
+
+var heldInputs = map[int][]InputEvent{
+	0:  {{Action: FAST_DOWN, Pressed: true}},
+	2:  {{Action: MOVE_RIGHT, Pressed: true}},
+	5:  {{Action: SONIC_DROP, Pressed: true}},
+	26: {{Action: MOVE_RIGHT, Pressed: false}},
+	36: {{Action: FAST_DOWN, Pressed: false}},
+}
+
+// A game resumed from a snapshot goes on exactly as the original one.
+func TestSnapshotResume(t *testing.T) {
+	const nbTicks, minSnapTick = 6000, 2500
+	for rt := RandomizerType(0); rt < NB_RANDOMIZERS; rt++ {
+		ga := GameNew(5)
+		ga.RandomizerType = rt
+		ga.KickTable = KickTableType(int(rt) % int(NB_KICK_TABLES))
+		ga.Gravity = GravityType(int(rt) % int(NB_GRAVITIES))
+		ga.Start()
+		ga.Recorder = ReplayNew(ga)
+		bo := BotNew()
+		bo.Delay = 40 * time.Millisecond
+		fileName := filepath.Join(t.TempDir(), "game.snp")
+		var inputs [][]InputEvent
+		var prevTetromino *Tetromino
+		spawnTick := -1
+		for tick := 0; tick < nbTicks && ga.Mode == PLAY; tick++ {
+			if spawnTick < 0 && tick >= minSnapTick && ga.CurTetromino != prevTetromino && ga.nbCompledLines == 0 {
+				spawnTick = tick
+			}
+			prevTetromino = ga.CurTetromino
+			if spawnTick >= 0 && tick == spawnTick+snapDelay {
+				if ga.CurTetromino == nil || !ga.fSonicDrop || !ga.fFastDown || !ga.fRightHeld {
+					t.Fatalf("%s: snapshot not taken mid-drop with moves held", rt)
+				}
+				if err := ga.Snapshot().Save(fileName); err != nil {
+					t.Fatal(err)
+				}
+			}
+			in := bo.Inputs(ga, TICK)
+			if spawnTick >= 0 {
+				in = append(in, heldInputs[tick-spawnTick]...)
+				if tick >= spawnTick+snapDelay {
+					inputs = append(inputs, in)
+				}
+			}
+			ga.Step(in)
+		}
+
+		sn, err := LoadSnapshot(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed := GameNew(0)
+		resumed.Restore(sn)
+		if resumed.Config != ga.Config {
+			t.Errorf("%s: resumed with config %+v, want %+v", rt, resumed.Config, ga.Config)
+		}
+		for _, in := range inputs {
+			resumed.Step(in)
+		}
+
+		if resumed.Tick != ga.Tick || resumed.CurScore != ga.CurScore || resumed.Lines != ga.Lines ||
+			!reflect.DeepEqual(resumed.Board, ga.Board) {
+			t.Errorf("%s: resumed game differs: score %d/%d, lines %d/%d", rt,
+				resumed.CurScore, ga.CurScore, resumed.Lines, ga.Lines)
+		}
+		for i, te := range ga.NextTetrominos {
+			if resumed.NextTetrominos[i].Typ != te.Typ {
+				t.Errorf("%s: next tetrominos differ", rt)
+				break
+			}
+		}
+		if len(resumed.Recorder.Inputs) != len(ga.Recorder.Inputs) {
+			t.Errorf("%s: replay has %d inputs, want %d", rt, len(resumed.Recorder.Inputs), len(ga.Recorder.Inputs))
+		}
+	}
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	ga := GameNew(1)
+	ga.Start()
+	sn := ga.Snapshot()
+	sn.Version = SNAPSHOT_VERSION + 1
+	fileName := filepath.Join(t.TempDir(), "game.snp")
+	if err := sn.Save(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(fileName); err == nil {
+		t.Error("snapshot of a newer version loaded")
+	}
+}
@@ -0,0 +1,211 @@
+package engine
+
+type Vector2i struct {
+	X int32
+	Y int32
+}
+
+var tetrominos = []Vector2i{
+	{0, 0}, {0, 0}, {0, 0}, {0, 0},
+	{0, -1}, {0, 0}, {-1, 0}, {-1, 1},
+	{0, -1}, {0, 0}, {1, 0}, {1, 1},
+	{0, -1}, {0, 0}, {0, 1}, {0, 2},
+	{-1, 0}, {0, 0}, {1, 0}, {0, 1},
+	{0, 0}, {1, 0}, {0, 1}, {1, 1},
+	{-1, -1}, {0, -1}, {0, 0}, {0, 1},
+	{1, -1}, {0, -1}, {0, 0}, {0, 1}}
+
+// SRS rotation state of each tetromino type as laid out in tetrominos.
+var spawnRotations = []int32{0, ROT_L, ROT_R, ROT_L, ROT_0, ROT_0, ROT_L, ROT_R}
+
+// Tetromino positions are expressed in board units (CELL_SIZE units per
+// cell), x from the left edge and y from the bottom edge of the board.
+type Tetromino struct {
+	Typ int32
+	X   int32
+	Y   int32
+	Rot int32
+	V   [4]Vector2i
+}
+
+func TetrominoNew(typ, x, y int32) *Tetromino {
+
+	//--
+	t := &Tetromino{Typ: typ, X: x, Y: y}
+	t.InitGfx()
+	return t
+}
+
+func (te *Tetromino) InitGfx() {
+
+	offSet := int(te.Typ) * len(te.V)
+	for i := 0; i < len(te.V); i++ {
+		te.V[i] = tetrominos[i+offSet]
+	}
+	te.Rot = spawnRotations[te.Typ]
+
+}
+
+func (te *Tetromino) RotateLeft() {
+	if te.Typ != 5 {
+		var x, y int32
+		for i := 0; i < len(te.V); i++ {
+			x = -te.V[i].Y
+			y = te.V[i].X
+			if te.Typ == 3 {
+				//-- I turns around the center of its 4x4 box
+				x++
+			}
+			te.V[i].X = x
+			te.V[i].Y = y
+		}
+		te.Rot = (te.Rot + 3) % 4
+	}
+}
+
+func (te *Tetromino) RotateRight() {
+	if te.Typ != 5 {
+		var x, y int32
+		for i := 0; i < len(te.V); i++ {
+			x = te.V[i].Y
+			y = -te.V[i].X
+			if te.Typ == 3 {
+				//-- I turns around the center of its 4x4 box
+				y++
+			}
+			te.V[i].X = x
+			te.V[i].Y = y
+		}
+		te.Rot = (te.Rot + 1) % 4
+	}
+}
+
+func (te *Tetromino) MinX() int32 {
+	var (
+		x    int32
+		minX int32
+	)
+	minX = te.V[0].X
+	for i := 1; i < len(te.V); i++ {
+		x = te.V[i].X
+		if x < minX {
+			minX = x
+		}
+	}
+	return minX
+}
+
+func (te *Tetromino) MaxX() int32 {
+	var (
+		x    int32
+		maxX int32
+	)
+	maxX = te.V[0].X
+	for i := 1; i < len(te.V); i++ {
+		x = te.V[i].X
+		if x > maxX {
+			maxX = x
+		}
+	}
+	return maxX
+}
+
+func (te *Tetromino) MaxY() int32 {
+	var (
+		y int32
+	)
+	maxY := te.V[0].Y
+	for i := 1; i < len(te.V); i++ {
+		y = te.V[i].Y
+		if y > maxY {
+			maxY = y
+		}
+	}
+	return maxY
+}
+
+func (te *Tetromino) MinY() int32 {
+	var (
+		y int32
+	)
+	minY := te.V[0].Y
+	for i := 1; i < len(te.V); i++ {
+		y = te.V[i].Y
+		if y < minY {
+			minY = y
+		}
+	}
+	return minY
+}
+
+func (te *Tetromino) Column() int32 {
+	return int32(te.X / CELL_SIZE)
+}
+
+func (te *Tetromino) IsOutLeftBoardLimit() bool {
+	l := te.MinX()*CELL_SIZE + te.X
+	return l < 0
+}
+
+func (te *Tetromino) IsOutRightBoardLimit() bool {
+	r := te.MaxX()*CELL_SIZE + CELL_SIZE + te.X
+	return r > NB_COLUMNS*CELL_SIZE
+}
+
+func (te *Tetromino) IsOutBoardLimit() bool {
+	return te.IsOutLeftBoardLimit() || te.IsOutRightBoardLimit() || te.IsOutBottomLimit()
+}
+
+func (te *Tetromino) IsOutBottomLimit() bool {
+	//--------------------------------------------------
+	y := te.MinY()*CELL_SIZE + te.Y - CELL_SIZE
+	return y <= 0
+}
+
+func (te *Tetromino) HitGround(board []int) bool {
+
+	//--------------------------------------------------
+	Hit := func(x int32, y int32) bool {
+		ix := int32(x / CELL_SIZE)
+		iy := int32(y / CELL_SIZE)
+		if (ix >= 0) && ix < NB_COLUMNS && (iy >= 0) && (iy < NB_ROWS) {
+			v := board[iy*NB_COLUMNS+ix]
+			if v != 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	offSet := int32(NB_ROWS * CELL_SIZE)
+
+	for _, v := range te.V {
+
+		x := v.X*CELL_SIZE + te.X + 1
+		y := offSet - (v.Y*CELL_SIZE + te.Y) + 1
+		if Hit(x, y) {
+			return true
+		}
+
+		x = v.X*CELL_SIZE + CELL_SIZE - 1 + te.X
+		y = offSet - (v.Y*CELL_SIZE + te.Y) + 1
+		if Hit(x, y) {
+			return true
+		}
+
+		x = v.X*CELL_SIZE + CELL_SIZE - 1 + te.X
+		y = offSet - (v.Y*CELL_SIZE + te.Y - CELL_SIZE + 1)
+		if Hit(x, y) {
+			return true
+		}
+
+		x = v.X*CELL_SIZE + te.X + 1
+		y = offSet - (v.Y*CELL_SIZE + te.Y - CELL_SIZE + 1)
+		if Hit(x, y) {
+			return true
+		}
+
+	}
+
+	return false
+}
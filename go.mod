@@ -3,13 +3,13 @@ module pixel_tetris
 go 1.21
 
 require (
+	github.com/faiface/beep v1.1.0
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/gopxl/pixel v1.0.0
 	golang.org/x/image v0.13.0
 )
 
 require (
-	github.com/faiface/beep v1.1.0 // indirect
 	github.com/faiface/glhf v0.0.0-20211013000516-57b20770c369 // indirect
 	github.com/faiface/mainthread v0.0.0-20171120011319-8b78f0a41ae3 // indirect
 	github.com/go-gl/gl v0.0.0-20211210172815-726fda9656d6 // indirect
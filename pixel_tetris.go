@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -8,6 +9,8 @@ import (
 	"os"
 	"time"
 
+	"pixel_tetris/engine"
+
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/effects"
 	"github.com/faiface/beep/speaker"
@@ -23,13 +26,19 @@ import (
 const (
 	LEFT       = 10
 	TOP        = 10
-	NB_ROWS    = 20
-	NB_COLUMNS = 12
+	NB_ROWS    = engine.NB_ROWS
+	NB_COLUMNS = engine.NB_COLUMNS
 	WIN_WIDTH  = 480
 	WIN_HEIGHT = 560
 	TITLE      = "Go Pixel Tetris"
 )
 
+// Upcoming tetrominos shown by default, out of engine.PREVIEW_SIZE.
+const NB_PREVIEWS = 3
+
+// Time the bot takes between two inputs, slow enough to be followed.
+const BOT_DELAY = 40 * time.Millisecond
+
 type GameMode int
 
 const (
@@ -38,159 +47,98 @@ const (
 	GAMEPAUSE
 	GAMEOVER
 	HIGHSCORES
+	REPLAY
+	RESULTS
+	CONTROLS
+	OPTIONS
 )
 
-type HightScore struct {
-	name  string
-	score int
-}
-
-type Vector2i struct {
-	x int32
-	y int32
-}
-
 type Color struct {
 	R, G, B, A uint8
 }
 
 var (
-	tetrominos []Vector2i
-	colors     []Color
+	colors []Color
 )
 
 type ProcessEvents_t func(win pixelgl.Window) bool
 
-type IsOutLimit_t func() bool
-
 type DrawMode_t func(win pixel.Target)
 
 var (
-	cellSize          int32
-	myRand            *rand.Rand
-	processEvents     ProcessEvents_t
-	isOutLRBoardLimit IsOutLimit_t
-	drawCurMode       DrawMode_t
-	tt_font           font.Face
-	atlas             *text.Atlas
-	successBuffer     *beep.Buffer
-	musicBuffer       *beep.Buffer
-	musicCtrl         *beep.Ctrl
-	musicVolume       *effects.Volume
-	idtetrominosBag   int
-	tetrominosBag     []int32
-	game              *Game
-	curTetromino      *Tetromino
-	nextTetromino     *Tetromino
-	startR            time.Time
+	cellSize      int32
+	myRand        = rand.New(rand.NewSource(time.Now().UnixNano()))
+	optSeed       int64
+	fOptSeed      bool
+	fOptPreviews  bool
+	optReplay     string
+	optPreviews   int
+	optStatic     bool
+	optRandomizer string
+	optBot        bool
+	processEvents ProcessEvents_t
+	drawCurMode   DrawMode_t
+	tt_font       font.Face
+	atlas         *text.Atlas
+	successBuffer *beep.Buffer
+	musicBuffer   *beep.Buffer
+	musicCtrl     *beep.Ctrl
+	musicVolume   *effects.Volume
+	game          *Game
+	controls      *Controls
+	playInputs    []engine.InputEvent
+	bot           *engine.Bot
+	startR        time.Time
 )
 
-func InitTetrominos() {
-
-	tetrominos = []Vector2i{
-		{0, 0}, {0, 0}, {0, 0}, {0, 0},
-		{0, -1}, {0, 0}, {-1, 0}, {-1, 1},
-		{0, -1}, {0, 0}, {1, 0}, {1, 1},
-		{0, -1}, {0, 0}, {0, 1}, {0, 2},
-		{-1, 0}, {0, 0}, {1, 0}, {0, 1},
-		{0, 0}, {1, 0}, {0, 1}, {1, 1},
-		{-1, -1}, {0, -1}, {0, 0}, {0, 1},
-		{1, -1}, {0, -1}, {0, 0}, {0, 1}}
-
-	colors = []Color{
-		{R: 0, G: 0, B: 0, A: 0xFF},
-		{R: 0xFF, G: 0x60, B: 0x60, A: 0xFF},
-		{R: 0x60, G: 0xFF, B: 0x60, A: 0xFF},
-		{R: 0x60, G: 0x60, B: 0xFF, A: 0xFF},
-		{R: 0xCC, G: 0xCC, B: 0x60, A: 0xFF},
-		{R: 0xCC, G: 0x60, B: 0xCC, A: 0xFF},
-		{R: 0x60, G: 0xCC, B: 0xCC, A: 0xFF},
-		{R: 0xDA, G: 0xAA, B: 0x00, A: 0xFF}}
-
-}
-
-func TetrisRandomizer() int32 {
-
-	var (
-		iSrc int32
-		ityp int32
-	)
-
-	if idtetrominosBag < 14 {
-		ityp = tetrominosBag[idtetrominosBag]
-		idtetrominosBag += 1
-	} else {
-		//-- Shuttle bag
-		for i := 0; i < 14; i++ {
-			iSrc = int32(myRand.Intn(14))
-			ityp = tetrominosBag[iSrc]
-			tetrominosBag[iSrc] = tetrominosBag[0]
-			tetrominosBag[0] = ityp
-		}
-		ityp = tetrominosBag[0]
-		idtetrominosBag = 1
+func NewSeed() int64 {
+	//--------------------------------------------------
+	if fOptSeed {
+		return optSeed
 	}
-
-	return ityp
+	//-- Keep random seeds short enough to be shared
+	return myRand.Int63n(1000000000)
 }
 
-func NewTetromino() {
-	//--------------------------------------------------
-	curTetromino = nextTetromino
-	curTetromino.x = 6 * cellSize
-	//curTetromino.y = NB_ROWS*cellSize - cellSize
-	curTetromino.y = WIN_HEIGHT - TOP + curTetromino.MaxY()*cellSize
-	nextTetromino = TetrominoNew(TetrisRandomizer(), (NB_COLUMNS+3)*cellSize, 10*cellSize)
+// ProcessEventsSound handles the music and volume controls, available on
+// every screen.
+func ProcessEventsSound(win pixelgl.Window) {
 
+	if controls.JustPressed(&win, CTL_MUSIC) {
+		speaker.Lock()
+		musicCtrl.Paused = !musicCtrl.Paused
+		speaker.Unlock()
+	} else if controls.JustPressed(&win, CTL_VOLUME_UP) {
+		settings.MusicVolume = min(settings.MusicVolume+1, MAX_VOLUME)
+		ApplySettings()
+		SaveSettings(SETTINGS_FILE)
+	} else if controls.JustPressed(&win, CTL_VOLUME_DOWN) {
+		settings.MusicVolume = max(settings.MusicVolume-1, 0)
+		ApplySettings()
+		SaveSettings(SETTINGS_FILE)
+	}
 }
 
 func ProcessEventsPlay(win pixelgl.Window) bool {
 
-	if win.JustPressed(pixelgl.KeyP) {
-		game.fPause = !game.fPause
-	} else if win.JustPressed(pixelgl.KeyLeft) {
-		game.velX = -1
-		isOutLRBoardLimit = curTetromino.IsOutLeftBoardLimit
-	} else if win.JustPressed(pixelgl.KeyRight) {
-		game.velX = 1
-		isOutLRBoardLimit = curTetromino.IsOutRightBoardLimit
-	} else if win.JustPressed(pixelgl.KeyUp) {
-		if curTetromino != nil {
-			curTetromino.RotateLeft()
-		}
-	} else if win.JustPressed(pixelgl.KeyDown) {
-		game.fFastDown = true
-	} else if win.JustPressed(pixelgl.KeySpace) {
-		if curTetromino != nil {
-			//-- Drop current Tetromino
-			game.fDrop = true
-		}
-	} else if win.JustPressed(pixelgl.KeyPause) {
-		speaker.Lock()
-		musicCtrl.Paused = !musicCtrl.Paused
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyKPAdd) {
-		speaker.Lock()
-		musicVolume.Volume += 0.5
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyKPSubtract) {
-		speaker.Lock()
-		musicVolume.Volume -= 0.5
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyEscape) {
+	if controls.JustPressed(&win, CTL_PAUSE) {
+		StartPause()
+		return true
+	}
+	playInputs = controls.PlayInputs(&win, playInputs)
+	ProcessEventsSound(win)
+
+	if controls.JustPressed(&win, CTL_GHOST) {
+		settings.Ghost = !settings.Ghost
+		ApplySettings()
+		SaveSettings(SETTINGS_FILE)
+	} else if controls.JustPressed(&win, CTL_BACK) {
+		//-- Keep the game to be continued
+		SuspendGame()
 		game.curMode = STANDBY
 		processEvents = ProcessEventsStandBy
 		drawCurMode = DrawStandByMode
-		curTetromino = nil
-		game.ClearBoard()
-		return false
-	}
-
-	if win.JustReleased(pixelgl.KeyLeft) || win.JustReleased(pixelgl.KeyRight) {
-		game.velX = 0
-		isOutLRBoardLimit = curTetromino.IsAlwaysOutBoardLimit
-	} else if win.JustReleased(pixelgl.KeyDown) {
-		game.fFastDown = false
+		game.Reset()
 	}
 
 	return true
@@ -198,98 +146,96 @@ func ProcessEventsPlay(win pixelgl.Window) bool {
 
 func ProcessEventsStandBy(win pixelgl.Window) bool {
 
-	if win.JustPressed(pixelgl.KeySpace) {
-		game.curMode = PLAY
-		processEvents = ProcessEventsPlay
-		drawCurMode = DrawPlayMode
-		NewTetromino()
-		game.curScore = 0
-	} else if win.JustPressed(pixelgl.KeyPause) {
-		speaker.Lock()
-		musicCtrl.Paused = !musicCtrl.Paused
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyKPAdd) {
-		speaker.Lock()
-		musicVolume.Volume += 0.5
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyKPSubtract) {
-		speaker.Lock()
-		musicVolume.Volume -= 0.5
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyEscape) {
+	if AnyJustPressed(&win) {
+		idleElapsed = 0
+		if demoGame != nil {
+			//-- The key only stops the demo
+			StopDemo()
+			return true
+		}
+	}
+
+	if controls.JustPressed(&win, CTL_START) {
+		StartGame()
+	} else if controls.JustPressed(&win, CTL_CONTINUE) && fSnapshot {
+		ResumeGame()
+	} else if controls.JustPressed(&win, CTL_REPLAY) && lastReplayFile != "" {
+		StartReplay(lastReplayFile)
+	} else if controls.JustPressed(&win, CTL_MENU_LEFT) {
+		game.Type = (game.Type + engine.NB_GAME_TYPES - 1) % engine.NB_GAME_TYPES
+	} else if controls.JustPressed(&win, CTL_MENU_RIGHT) {
+		game.Type = (game.Type + 1) % engine.NB_GAME_TYPES
+	} else if (controls.JustPressed(&win, CTL_MENU_UP) || controls.JustPressed(&win, CTL_MENU_DOWN)) && game.Type == engine.ULTRA {
+		if game.UltraTime == 2*time.Minute {
+			game.UltraTime = 3 * time.Minute
+		} else {
+			game.UltraTime = 2 * time.Minute
+		}
+	} else if controls.JustPressed(&win, CTL_HIGHSCORES) {
+		game.idHighScore = -1
+		game.curMode = HIGHSCORES
+		processEvents = ProcessEventsHightScores
+		drawCurMode = DrawHighScoresMode
+	} else if controls.JustPressed(&win, CTL_CONTROLS) {
+		StartControlsMode()
+	} else if controls.JustPressed(&win, CTL_OPTIONS) {
+		StartOptionsMode()
+	} else if controls.JustPressed(&win, CTL_BACK) {
 		game.fQuitGame = true
+	} else {
+		ProcessEventsSound(win)
 	}
 	return true
 }
 
 func ProcessEventsGameOver(win pixelgl.Window) bool {
 
-	if win.JustPressed(pixelgl.KeySpace) {
+	if controls.JustPressed(&win, CTL_START) {
 		game.curMode = STANDBY
 		processEvents = ProcessEventsStandBy
 		drawCurMode = DrawStandByMode
-		curTetromino = nil
-		game.ClearBoard()
-	} else if win.JustPressed(pixelgl.KeyPause) {
-		speaker.Lock()
-		musicCtrl.Paused = !musicCtrl.Paused
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyKPAdd) {
-		speaker.Lock()
-		musicVolume.Volume += 0.5
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyKPSubtract) {
-		speaker.Lock()
-		musicVolume.Volume -= 0.5
-		speaker.Unlock()
-
+		game.Reset()
+	} else {
+		ProcessEventsSound(win)
 	}
 	return true
 }
 
 func ProcessEventsHightScores(win pixelgl.Window) bool {
 
-	if win.JustPressed(pixelgl.KeyEnter) || win.JustPressed(pixelgl.KeyKPEnter) {
-		game.SaveHighScores("HighScores.txt")
+	if controls.JustPressed(&win, CTL_VALIDATE) {
+		game.SaveHighScores(HIGHSCORES_FILE)
 		game.curMode = STANDBY
 		processEvents = ProcessEventsStandBy
 		drawCurMode = DrawStandByMode
-	} else if win.JustPressed(pixelgl.KeyPause) {
-		speaker.Lock()
-		musicCtrl.Paused = !musicCtrl.Paused
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyKPAdd) {
-		speaker.Lock()
-		musicVolume.Volume += 0.5
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyKPSubtract) {
-		speaker.Lock()
-		musicVolume.Volume -= 0.5
-		speaker.Unlock()
-	} else if win.JustPressed(pixelgl.KeyBackspace) {
+	} else if controls.JustPressed(&win, CTL_MENU_LEFT) {
+		game.idBoard = (game.idBoard + len(game.leaderboards) - 1) % len(game.leaderboards)
+	} else if controls.JustPressed(&win, CTL_MENU_RIGHT) {
+		game.idBoard = (game.idBoard + 1) % len(game.leaderboards)
+	} else if controls.JustPressed(&win, CTL_DELETE) {
 		sz := len(game.userName)
-		if sz > 0 {
+		if sz > 0 && game.idHighScore >= 0 {
 			game.userName = game.userName[:sz-1]
-			game.highScores[game.idHighScore].name = game.userName
+			game.HighScoreEntry().Name = game.userName
 		}
-	} else if win.JustPressed(pixelgl.KeyEscape) {
+	} else if controls.JustPressed(&win, CTL_BACK) {
 		if len(game.userName) == 0 && game.idHighScore >= 0 {
-			game.highScores[game.idHighScore].name = "XXXXXX"
+			game.HighScoreEntry().Name = "XXXXXX"
 		}
-		game.SaveHighScores("HighScores.txt")
+		game.SaveHighScores(HIGHSCORES_FILE)
 		game.curMode = STANDBY
 		processEvents = ProcessEventsStandBy
 		drawCurMode = DrawStandByMode
 	} else {
-		for _, k := range game.tblKeyChars {
-			if win.JustPressed(k.keycode) {
-				if game.idHighScore >= 0 {
-					if len(game.userName) < 10 {
-						game.userName += k.c
-						game.highScores[game.idHighScore].name = game.userName
-					}
-				}
+		ProcessEventsSound(win)
+		typed := controls.Typed(&win, CTL_VALIDATE, CTL_MENU_LEFT, CTL_MENU_RIGHT, CTL_DELETE, CTL_BACK,
+			CTL_MUSIC, CTL_VOLUME_UP, CTL_VOLUME_DOWN)
+		if typed != "" && game.idHighScore >= 0 {
+			game.userName += typed
+			if len(game.userName) > 10 {
+				game.userName = game.userName[:10]
 			}
+			game.HighScoreEntry().Name = game.userName
 		}
 	}
 
@@ -321,14 +267,49 @@ func loadTTF(path string, size float64) (font.Face, error) {
 
 func DrawPlayMode(win pixel.Target) {
 
-	if curTetromino != nil {
-		curTetromino.Draw(win)
+	if game.CurTetromino != nil {
+		if game.fShowGhost {
+			DrawGhostTetromino(win, game.GhostTetromino())
+		}
+		DrawTetromino(win, game.CurTetromino, game.CurTetromino.X, game.RenderY())
+	}
+
+}
+
+func DrawPanelTetromino(win pixel.Target, label string, te *engine.Tetromino, oy int32) {
+
+	ox := (NB_COLUMNS + 3) * cellSize
+	DrawTetromino(win, te, ox, oy)
+
+	y := float64(WIN_HEIGHT-TOP-NB_ROWS*cellSize+oy) + 2.5*float64(cellSize)
+	txt := text.New(pixel.V(float64(LEFT+ox), y), atlas)
+	txt.Color = colornames.Gold
+	fmt.Fprintf(txt, "%s", label)
+	txt.Draw(win, pixel.IM.Moved(pixel.V(-txt.Bounds().W()/2+float64(cellSize)/2, 0)))
+
+}
+
+// DrawPreview shows the next tetromino in the panel and the following ones
+// at half size along the right edge of the window.
+func DrawPreview(win pixel.Target) {
+
+	DrawPanelTetromino(win, "NEXT", game.NextTetrominos[0], 10*cellSize)
+
+	x := float64(WIN_WIDTH - cellSize)
+	y := float64(WIN_HEIGHT-TOP-NB_ROWS*cellSize) + 9*float64(cellSize)
+	for _, te := range game.NextTetrominos[1:game.nbPreviews] {
+		DrawMiniTetromino(win, te, x, y)
+		y -= 2 * float64(cellSize)
 	}
 
 }
 
 func DrawStandByMode(win pixel.Target) {
 
+	if demoGame != nil {
+		DrawDemo(win)
+	}
+
 	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
 	oy := float64(WIN_HEIGHT - TOP - 7*cellSize)
 	txt := text.New(pixel.V(ox, oy), atlas)
@@ -341,7 +322,46 @@ func DrawStandByMode(win pixel.Target) {
 	txt = text.New(pixel.V(ox, oy), atlas)
 	txt.Color = colornames.Gold
 	rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
-	fmt.Fprintf(txt, "Press SPACE to PLAY")
+	fmt.Fprintf(txt, "Press %s to PLAY", controls.KeyName(CTL_START))
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	oy -= float64(cellSize + 4)
+	txt = text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Orange
+	rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "< %s >", game.ModeName())
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	if fSnapshot {
+		oy -= float64(cellSize + 4)
+		txt = text.New(pixel.V(ox, oy), atlas)
+		txt.Color = colornames.Gold
+		rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+		fmt.Fprintf(txt, "%s to CONTINUE", controls.KeyName(CTL_CONTINUE))
+		txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+	}
+
+	if lastReplayFile != "" {
+		oy -= float64(cellSize + 4)
+		txt = text.New(pixel.V(ox, oy), atlas)
+		txt.Color = colornames.Gold
+		rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+		fmt.Fprintf(txt, "%s to watch the last game", controls.KeyName(CTL_REPLAY))
+		txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+	}
+
+	oy -= float64(cellSize + 4)
+	txt = text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Gold
+	rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "%s for the high scores", controls.KeyName(CTL_HIGHSCORES))
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	oy -= float64(cellSize + 4)
+	txt = text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Gold
+	rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "%s for the options", controls.KeyName(CTL_OPTIONS))
 	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
 
 }
@@ -360,7 +380,22 @@ func DrawGameOverMode(win pixel.Target) {
 	txt = text.New(pixel.V(ox, oy), atlas)
 	txt.Color = colornames.Gold
 	rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
-	fmt.Fprintf(txt, "Press SPACE to Continue")
+	fmt.Fprintf(txt, "Press %s to Continue", controls.KeyName(CTL_START))
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
+	DrawSeed(win, oy-float64(2*cellSize+4), game.Seed)
+
+}
+
+// DrawSeed shows the seed of the game just played, on the line at oy, for
+// the player to play the same sequence again with -seed.
+func DrawSeed(win pixel.Target, oy float64, seed int64) {
+
+	ox := float64(LEFT + (NB_COLUMNS/2)*cellSize)
+	txt := text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Gold
+	rect := pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "SEED : %d", seed)
 	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
 
 }
@@ -375,28 +410,42 @@ func DrawHighScoresMode(win pixel.Target) {
 	fmt.Fprintf(txt, "HIGH SCORES")
 	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
 
+	lb := game.leaderboards[game.idBoard]
+	oy -= float64(cellSize + 4)
+	txt = text.New(pixel.V(ox, oy), atlas)
+	txt.Color = colornames.Orange
+	rect = pixel.R(LEFT, oy, float64(LEFT+NB_COLUMNS*cellSize), oy+float64(cellSize))
+	fmt.Fprintf(txt, "< %s >", lb.mode)
+	txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
+
 	x1 := float64(LEFT + 4)
 	x2 := float64(LEFT + NB_COLUMNS*cellSize/2)
 	x3 := float64(LEFT + NB_COLUMNS*cellSize - 4)
-	for i, h := range game.highScores {
+	for i, h := range lb.scores {
 		lineColor := colornames.Gold
-		if game.idHighScore == i && game.iColorHighScore%2 != 0 {
+		fEntry := game.idHighScoreBoard == game.idBoard && game.idHighScore == i
+		if fEntry && game.iColorHighScore%2 != 0 {
 			lineColor = colornames.Orange
 		}
 		oy -= float64(cellSize + 4)
 		txt = text.New(pixel.V(ox, oy), atlas)
 		txt.Color = lineColor
 		rect = pixel.R(x1, oy, x2-4, oy)
-		fmt.Fprintf(txt, "%s", h.name)
+		fmt.Fprintf(txt, "%s", h.Name)
 		txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
 		txt = text.New(pixel.V(ox, oy), atlas)
 		txt.Color = lineColor
 		rect = pixel.R(x2+4, oy, x3, oy)
-		fmt.Fprintf(txt, "%06d", h.score)
+		fmt.Fprintf(txt, "%s", lb.format(h))
 		txt.Draw(win, pixel.IM.Moved(rect.Bounds().Center().Sub(txt.Bounds().Center())))
 
 	}
 
+	//-- Right after a game, the seed of the score being named
+	if h := game.HighScoreEntry(); h != nil {
+		DrawSeed(win, oy-float64(2*cellSize), h.Seed)
+	}
+
 	elapsedR := time.Since(startR)
 	if elapsedR.Milliseconds() > 500 {
 		startR = time.Now()
@@ -408,7 +457,8 @@ func DrawHighScoresMode(win pixel.Target) {
 func PlaySuccesSound() {
 	//-----------------------------------------
 	shot := successBuffer.Streamer(0, successBuffer.Len())
-	volume := &effects.Volume{Streamer: shot, Base: 2, Volume: -2}
+	volume := &effects.Volume{Streamer: shot, Base: 2}
+	volume.Volume, volume.Silent = volumeOf(settings.SfxVolume)
 	speaker.Play(volume)
 
 }
@@ -468,285 +518,195 @@ func run() {
 		panic(err)
 	}
 	//--
-	tetrominosBag = make([]int32, 14)
-	tetrominosBag = []int32{
-		1, 2, 3, 4, 5, 6, 7, 1, 2, 3, 4, 5, 6, 7,
-	}
-	idtetrominosBag = 14
-
-	cellSize = int32(WIN_WIDTH / (NB_COLUMNS + 7))
-
-	InitTetrominos()
-	myRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	cellSize = engine.CELL_SIZE
 
 	game = GameNew()
-	curTetromino = nil
-	nextTetromino = TetrominoNew(TetrisRandomizer(), (NB_COLUMNS+3)*cellSize, 10*cellSize)
-	game.LoadHighScores("HighScores.txt")
+	controls = ControlsNew()
+	controls.Load(CONTROLS_FILE)
+	game.StaticPreview = optStatic
+	if optBot {
+		bot = engine.BotNew()
+		bot.Delay = BOT_DELAY
+	}
+	game.LoadHighScores(HIGHSCORES_FILE)
+	if _, err := os.Stat(SNAPSHOT_FILE); err == nil {
+		fSnapshot = true
+	}
+	LoadSettings(SETTINGS_FILE)
+	if fOptPreviews {
+		settings.NbPreviews = min(max(optPreviews, 1), engine.PREVIEW_SIZE)
+	}
+	if optRandomizer != "" {
+		rt, ok := engine.RandomizerTypeOf(optRandomizer)
+		if !ok {
+			log.Fatalf("unknown randomizer %q", optRandomizer)
+		}
+		settings.Randomizer = rt
+	}
+	ApplySettings()
 
 	atlas = text.NewAtlas(tt_font, text.ASCII)
 	txt := text.New(pixel.V(10, 20), atlas)
 	txt.Color = colornames.Gold
 	fmt.Fprintf(txt, "SCORE : %06d", 100)
 
-	startV := time.Now()
-	startH := startV
-	startR = startV
+	startR = time.Now()
+	lastFrame := startR
 
 	game.curMode = STANDBY
 	processEvents = ProcessEventsStandBy
 	drawCurMode = DrawStandByMode
 
-	isOutLRBoardLimit = curTetromino.IsAlwaysOutBoardLimit
+	if optReplay != "" {
+		lastReplayFile = optReplay
+		StartReplay(optReplay)
+	}
 
 	for !win.Closed() {
 
-		if !processEvents(*win) {
-			//-- Manage Escape from PLAY mode
-			if game.curScore != 0 {
-				id := game.IsHightScore(game.curScore)
-				//-- Manage Game Over and User Escape
-				if id >= 0 {
-					//--
-					game.InsertHightScore(id, game.userName, game.curScore)
-					game.curMode = HIGHSCORES
-					processEvents = ProcessEventsHightScores
-					drawCurMode = DrawHighScoresMode
-					game.ClearBoard()
-					curTetromino = nil
-				} else {
-					//--
-					game.ClearBoard()
-					curTetromino = nil
-					game.curMode = STANDBY
-					processEvents = ProcessEventsStandBy
-					drawCurMode = DrawStandByMode
-				}
-
-			}
-
-		}
+		processEvents(*win)
 
 		if game.fQuitGame {
 			break
 		}
 
-		if game.curMode == PLAY {
+		now := time.Now()
+		dt := now.Sub(lastFrame)
+		lastFrame = now
+
+		if game.curMode == REPLAY {
+			UpdateReplay(dt)
+		} else if game.curMode == STANDBY {
+			UpdateDemo(dt)
+		} else if game.curMode == PLAY || game.curMode == GAMEPAUSE {
+			if game.curMode == PLAY && !win.Focused() {
+				StartPause()
+			}
 			//-- Update game state
+			if bot != nil && game.curMode == PLAY {
+				playInputs = append(playInputs, bot.Inputs(game.Game, dt)...)
+			}
+			events := game.Update(dt, playInputs)
+			playInputs = playInputs[:0]
 
-			elapsedV := time.Since(startV)
-			elapsedR := time.Since(startR)
-
-			if game.nbCompledLines > 0 {
-				//-- Remove Completed lines
-				if elapsedV.Milliseconds() > 250 {
-					startV = time.Now()
-					game.nbCompledLines--
-					game.EraseFirstCompletedLine()
+			for _, ev := range events {
+				switch ev {
+				case engine.EV_LINE_ERASED:
 					PlaySuccesSound()
-				}
-			} else if game.horizontalMove != 0 {
-				//-- Move to the next slot
-				elapsed := time.Since(startH)
-				if elapsed.Milliseconds() > 20 {
-					startH = time.Now()
-
-					for iOffSet := 0; iOffSet < int(4); iOffSet++ {
-
-						backupX := curTetromino.x
-						curTetromino.x += game.horizontalMove
-
-						if game.horizontalMove < 0 {
-							isOutLRBoardLimit = curTetromino.IsOutLeftBoardLimit
-						} else {
-							// game.horizontalMove > 0
-							isOutLRBoardLimit = curTetromino.IsOutRightBoardLimit
-						}
-
-						if isOutLRBoardLimit() {
-							curTetromino.x = backupX
-							game.horizontalMove = 0
-							break
-						} else {
-							if curTetromino.HitGround(game.board) {
-								curTetromino.x = backupX
-								game.horizontalMove = 0
-								break
-							}
-						}
-
-						if game.horizontalMove != 0 {
-							if game.horizontalStartColumn != curTetromino.Column() {
-								curTetromino.x = backupX
-								game.horizontalMove = 0
-								startH = time.Now()
-								break
-							}
-						}
-
-					}
-				}
-
-			} else if game.fDrop {
-				//-- Drop Tetromino
-				if elapsedV.Milliseconds() > 10 {
-					startV = time.Now()
-					for iOffSet := 0; iOffSet < 6; iOffSet++ {
-						//-- Move down to check
-						curTetromino.y--
-						if curTetromino.HitGround(game.board) {
-							curTetromino.y++
-							game.FreezeTetromino(curTetromino)
-							NewTetromino()
-							game.fDrop = false
-						} else if curTetromino.IsOutBottomLimit() {
-							curTetromino.y++
-							game.FreezeTetromino(curTetromino)
-							NewTetromino()
-							game.fDrop = false
-						}
-						if game.fDrop {
-							if game.velX != 0 {
-								elapsed := time.Since(startH)
-
-								if elapsed.Milliseconds() > 20 {
-
-									backupX := curTetromino.x
-									curTetromino.x += game.velX
-
-									if isOutLRBoardLimit() {
-										curTetromino.x = backupX
-									} else {
-										if curTetromino.HitGround(game.board) {
-											curTetromino.x = backupX
-										} else {
-											startH = time.Now()
-											game.horizontalMove = game.velX
-											game.horizontalStartColumn = curTetromino.Column()
-											break
-										}
-									}
-
-								}
-
-							}
-
-						}
-
+				case engine.EV_CLEAR:
+					ShowClearLabels(game.LastClear)
+				case engine.EV_GAMEOVER:
+					//--
+					h := game.NewHighScore()
+					id := -1
+					if bot == nil {
+						id = game.IsHightScore(h)
 					}
-				}
 
-			} else {
-				//-- Move down Tetromino
-				var limitElapse int64 = 25
-				if game.fFastDown {
-					limitElapse = 10
-				}
-
-				if elapsedV.Milliseconds() > limitElapse {
-					startV = time.Now()
-					if curTetromino.HitGround(game.board) {
-						fmt.Println("-----")
+					if id >= 0 {
+						//--
+						game.InsertHightScore(id, h)
+						game.curMode = HIGHSCORES
+						processEvents = ProcessEventsHightScores
+						drawCurMode = DrawHighScoresMode
+					} else {
+						//--
+						game.curMode = GAMEOVER
+						processEvents = ProcessEventsGameOver
+						drawCurMode = DrawGameOverMode
 					}
-					for iOffSet := 0; iOffSet < 3; iOffSet++ {
-						//-- Move down to check
-						curTetromino.y--
-						fMove := true
-						if curTetromino.HitGround(game.board) {
-							curTetromino.y++
-							game.FreezeTetromino(curTetromino)
-							NewTetromino()
-							fMove = false
-
-						} else if curTetromino.IsOutBottomLimit() {
-							curTetromino.y++
-							game.FreezeTetromino(curTetromino)
-							NewTetromino()
-							fMove = false
-						}
-						if fMove {
-							if game.velX != 0 {
-								elapsed := time.Since(startH)
-								if elapsed.Milliseconds() > 15 {
-
-									backupX := curTetromino.x
-									curTetromino.x += game.velX
-
-									if isOutLRBoardLimit() {
-										curTetromino.x = backupX
-									} else {
-										if curTetromino.HitGround(game.board) {
-											curTetromino.x = backupX
-										} else {
-											startH = time.Now()
-											game.horizontalMove = game.velX
-											game.horizontalStartColumn = curTetromino.Column()
-											break
-										}
-									}
-
-								}
-							}
-						}
+					SaveRecordedReplay()
+					game.Reset()
+				case engine.EV_FINISHED:
+					//--
+					game.idHighScore = -1
+					h := game.NewHighScore()
+					if id := game.IsHightScore(h); id >= 0 && bot == nil {
+						game.InsertHightScore(id, h)
 					}
+					game.curMode = RESULTS
+					processEvents = ProcessEventsResults
+					drawCurMode = DrawResultsMode
+					SaveRecordedReplay()
+					game.Reset()
 				}
 			}
 
-			//-- Check Game Over
-			if game.IsGameOver() {
-
-				//--
-				id := game.IsHightScore(game.curScore)
+		}
 
-				if id >= 0 {
-					//--
-					game.InsertHightScore(id, game.userName, game.curScore)
-					game.curMode = HIGHSCORES
-					processEvents = ProcessEventsHightScores
-					drawCurMode = DrawHighScoresMode
-					game.ClearBoard()
-					curTetromino = nil
-				} else {
-					//--
-					game.curMode = GAMEOVER
-					processEvents = ProcessEventsGameOver
-					drawCurMode = DrawGameOverMode
-					game.ClearBoard()
-					curTetromino = nil
+		win.Clear(backgroundColor)
 
-				}
+		if fPaused {
+			//-- No planning moves while paused
+			game.DrawBackground(win)
+		} else {
+			game.DrawBoard(win)
 
+			if len(game.NextTetrominos) > 0 {
+				DrawPreview(win)
 			}
-
-			if elapsedR.Milliseconds() > 500 {
-				startR = time.Now()
-				nextTetromino.RotateRight()
+			if game.HoldTetromino != nil {
+				DrawPanelTetromino(win, "HOLD", game.HoldTetromino, 16*cellSize)
 			}
-
 		}
 
-		win.Clear(colornames.Darkblue)
-
-		game.DrawBoard(win)
-
-		if nextTetromino != nil {
-			nextTetromino.Draw(win)
+		if game.curMode == PLAY || game.curMode == GAMEPAUSE || game.curMode == REPLAY {
+			switch game.Type {
+			case engine.SPRINT:
+				DrawSprintPanel(win)
+			case engine.ULTRA:
+				DrawUltraPanel(win)
+			}
 		}
 
 		drawCurMode(win)
 
+		if game.curMode == PLAY || game.curMode == REPLAY {
+			DrawClearLabels(win)
+		}
+
 		//-- Draw current score
 		txt := text.New(pixel.V(10, 20), atlas)
 		txt.Color = colornames.Gold
-		fmt.Fprintf(txt, "SCORE : %06d", game.curScore)
+		fmt.Fprintf(txt, "SCORE : %06d", game.CurScore)
+		txt.Draw(win, pixel.IM)
+
+		txt = text.New(pixel.V(200, 20), atlas)
+		txt.Color = colornames.Gold
+		fmt.Fprintf(txt, "LEVEL : %02d", game.Level)
+		txt.Draw(win, pixel.IM)
+
+		txt = text.New(pixel.V(340, 20), atlas)
+		txt.Color = colornames.Gold
+		fmt.Fprintf(txt, "LINES : %03d", game.Lines)
 		txt.Draw(win, pixel.IM)
 
 		win.Update()
 
 	}
 
+	//-- Closing the window keeps the game in progress
+	if game.curMode == PLAY || fPaused {
+		SuspendGame()
+	}
+
 }
 
 func main() {
+	flag.Int64Var(&optSeed, "seed", 0, "seed of the tetromino sequence, the same for every game")
+	flag.StringVar(&optReplay, "replay", "", "replay file to play back at startup")
+	flag.IntVar(&optPreviews, "previews", NB_PREVIEWS, "number of upcoming tetrominos shown, 1 to 6")
+	flag.StringVar(&optRandomizer, "randomizer", "", "tetromino randomizer: 7-bag, 14-bag, random, nes or tgm")
+	flag.BoolVar(&optStatic, "static-preview", false, "show the next tetromino in spawn orientation instead of spinning")
+	flag.BoolVar(&optBot, "bot", false, "let the computer play, off the high scores")
+	flag.Parse()
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "seed":
+			fOptSeed = true
+		case "previews":
+			fOptPreviews = true
+		}
+	})
 	pixelgl.Run(run)
 }